@@ -0,0 +1,537 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"svm/auth/authz"
+	authJWT "svm/auth/jwt"
+	"svm/auth/security"
+	authToken "svm/auth/token"
+	smvmmiddleware "svm/middleware"
+	"svm/models/db_models"
+	"time"
+)
+
+// Audit log actions
+const (
+	AuditSuspendUser   = "suspend_user"
+	AuditUnsuspendUser = "unsuspend_user"
+	AuditForceLogout   = "force_logout"
+	AuditResetMFA      = "reset_mfa"
+	AuditImpersonate   = "impersonate"
+	AuditUnlockAccount = "unlock_account"
+)
+
+// AdminUserResponse represents a user as seen by administrators
+type AdminUserResponse struct {
+	ID              uint       `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Role            string     `json:"role"`
+	EmailVerified   bool       `json:"email_verified"`
+	TOTPEnabled     bool       `json:"totp_enabled"`
+	SuspendedAt     *time.Time `json:"suspended_at,omitempty"`
+	SuspendedReason string     `json:"suspended_reason,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// AdminActionRequest carries the reason an administrator gives for an action
+type AdminActionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ImpersonationResponse represents the structure for the impersonation token response
+type ImpersonationResponse struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// AuditLogResponse represents one entry of the audit trail
+type AuditLogResponse struct {
+	ID           uint      `json:"id"`
+	ActorID      uint      `json:"actor_id"`
+	Action       string    `json:"action"`
+	TargetUserID uint      `json:"target_user_id"`
+	Details      string    `json:"details"`
+	IP           string    `json:"ip"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SearchUsers godoc
+// @Summary      Search users
+// @Description  Search users by name or email. Admin only.
+// @Security     BearerAuth
+// @Tags         admin
+// @Produce      json
+// @Param        q        query     string  false  "Part of the name or email"
+// @Param        page     query     int     false  "Page number"
+// @Param        pageSize query     int     false  "Number of users per page"
+// @Success      200  {array}   AdminUserResponse
+// @Failure      403  {string}  string "Forbidden"
+// @Failure      500  {string}  string "Failed to search users"
+// @Router       /api/admin/users [get]
+func SearchUsers(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, pageSize := pagination(r)
+
+		query := db.Model(&db_models.User{}).Order("id")
+		if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+			pattern := "%" + strings.ToLower(q) + "%"
+			query = query.Where("LOWER(name) LIKE ? OR LOWER(email) LIKE ?", pattern, pattern)
+		}
+
+		var users []db_models.User
+		if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Find(&users).Error; err != nil {
+			http.Error(w, "Failed to search users", http.StatusInternalServerError)
+			return
+		}
+
+		response := []AdminUserResponse{}
+		for _, user := range users {
+			response = append(response, adminUserResponse(user))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// SuspendUser godoc
+// @Summary      Suspend a user
+// @Description  Block the user from logging in and end all of their sessions. Admin only.
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                 true   "User ID"
+// @Param        request body      AdminActionRequest  false  "Reason"
+// @Success      200  {object}  AdminUserResponse
+// @Failure      400  {string}  string "Administrators cannot suspend themselves"
+// @Failure      403  {string}  string "Forbidden"
+// @Failure      404  {string}  string "User not found"
+// @Failure      500  {string}  string "Failed to suspend user"
+// @Router       /api/admin/users/{id}/suspend [post]
+func SuspendUser(db *gorm.DB, tokenStore *authToken.TokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		request, ok := decodeAdminAction(w, r)
+		if !ok {
+			return
+		}
+
+		user, ok := loadTargetUser(w, r, db)
+		if !ok {
+			return
+		}
+		if principal.IsSelf(user.ID) {
+			http.Error(w, "Administrators cannot suspend themselves", http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		user.SuspendedAt = &now
+		user.SuspendedReason = request.Reason
+		if err := db.Model(&user).Updates(map[string]interface{}{
+			"SuspendedAt":     user.SuspendedAt,
+			"SuspendedReason": user.SuspendedReason,
+		}).Error; err != nil {
+			http.Error(w, "Failed to suspend user", http.StatusInternalServerError)
+			return
+		}
+
+		// Askıya alınan kullanıcının açık oturumları hemen kapatılır
+		if err := forceLogout(tokenStore, user.ID); err != nil {
+			http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+
+		if !recordAudit(w, r, db, AuditSuspendUser, user.ID, request.Reason) {
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(adminUserResponse(user))
+	}
+}
+
+// UnsuspendUser godoc
+// @Summary      Unsuspend a user
+// @Description  Allow a suspended user to log in again. Admin only.
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                 true   "User ID"
+// @Param        request body      AdminActionRequest  false  "Reason"
+// @Success      200  {object}  AdminUserResponse
+// @Failure      403  {string}  string "Forbidden"
+// @Failure      404  {string}  string "User not found"
+// @Failure      500  {string}  string "Failed to unsuspend user"
+// @Router       /api/admin/users/{id}/unsuspend [post]
+func UnsuspendUser(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, ok := decodeAdminAction(w, r)
+		if !ok {
+			return
+		}
+
+		user, ok := loadTargetUser(w, r, db)
+		if !ok {
+			return
+		}
+
+		user.SuspendedAt = nil
+		user.SuspendedReason = ""
+		if err := db.Model(&user).Updates(map[string]interface{}{
+			"SuspendedAt":     nil,
+			"SuspendedReason": "",
+		}).Error; err != nil {
+			http.Error(w, "Failed to unsuspend user", http.StatusInternalServerError)
+			return
+		}
+
+		if !recordAudit(w, r, db, AuditUnsuspendUser, user.ID, request.Reason) {
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(adminUserResponse(user))
+	}
+}
+
+// ForceLogout godoc
+// @Summary      Force logout a user
+// @Description  Revoke every session and access token of the user and close their WebSocket connection. Admin only.
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Param        id      path      int                 true   "User ID"
+// @Param        request body      AdminActionRequest  false  "Reason"
+// @Success      204  "No Content"
+// @Failure      403  {string}  string "Forbidden"
+// @Failure      404  {string}  string "User not found"
+// @Failure      500  {string}  string "Failed to revoke sessions"
+// @Router       /api/admin/users/{id}/logout [post]
+func ForceLogout(db *gorm.DB, tokenStore *authToken.TokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, ok := decodeAdminAction(w, r)
+		if !ok {
+			return
+		}
+
+		user, ok := loadTargetUser(w, r, db)
+		if !ok {
+			return
+		}
+
+		if err := forceLogout(tokenStore, user.ID); err != nil {
+			http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+
+		if !recordAudit(w, r, db, AuditForceLogout, user.ID, request.Reason) {
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ResetMFA godoc
+// @Summary      Reset two-factor authentication
+// @Description  Disable TOTP and SMS codes and delete the recovery codes of a user who lost their authenticator or phone. Admin only.
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Param        id      path      int                 true   "User ID"
+// @Param        request body      AdminActionRequest  false  "Reason"
+// @Success      204  "No Content"
+// @Failure      403  {string}  string "Forbidden"
+// @Failure      404  {string}  string "User not found"
+// @Failure      500  {string}  string "Failed to reset two-factor authentication"
+// @Router       /api/admin/users/{id}/mfa/reset [post]
+func ResetMFA(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, ok := decodeAdminAction(w, r)
+		if !ok {
+			return
+		}
+
+		user, ok := loadTargetUser(w, r, db)
+		if !ok {
+			return
+		}
+
+		if err := disableTOTP(db, user.ID); err != nil {
+			http.Error(w, "Failed to reset two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+		if err := db.Model(&user).Update("SMSMFAEnabled", false).Error; err != nil {
+			http.Error(w, "Failed to reset two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+
+		event := securityEvent(r, security.EventMFADisabled, user.ID)
+		event.Details = "reset by an administrator"
+		security.Record(event)
+
+		if !recordAudit(w, r, db, AuditResetMFA, user.ID, request.Reason) {
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Impersonate godoc
+// @Summary      Impersonate a user
+// @Description  Issue a short-lived access token acting as the user, for support. The token cannot be refreshed and does not grant the security or sessions scopes. A reason is required and the action is audited. Admin only.
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                 true  "User ID"
+// @Param        request body      AdminActionRequest  true  "Reason"
+// @Success      200  {object}  ImpersonationResponse
+// @Failure      400  {string}  string "A reason is required"
+// @Failure      403  {string}  string "Forbidden"
+// @Failure      404  {string}  string "User not found"
+// @Failure      500  {string}  string "Failed to generate impersonation token"
+// @Router       /api/admin/users/{id}/impersonate [post]
+func Impersonate(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		request, ok := decodeAdminAction(w, r)
+		if !ok {
+			return
+		}
+		if strings.TrimSpace(request.Reason) == "" {
+			http.Error(w, "A reason is required", http.StatusBadRequest)
+			return
+		}
+
+		// Impersonation token'ı ile başka birine geçilemez
+		if principal.IsImpersonated() {
+			smvmmiddleware.Forbidden(w)
+			return
+		}
+
+		id, ok := targetUserID(w, r)
+		if !ok {
+			return
+		}
+		var user db_models.User
+		if err := db.Preload("Friends").First(&user, id).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		// Yöneticiler birbirlerinin yerine geçemez
+		if principal.IsSelf(user.ID) || user.Role == db_models.RoleAdmin {
+			smvmmiddleware.Forbidden(w)
+			return
+		}
+
+		accessToken, err := authJWT.GenerateImpersonationToken(user.ID, user.Email, user.Name, string(user.Role), getUserFriendsAsEmails(user), principal.UserID)
+		if err != nil {
+			http.Error(w, "Failed to generate impersonation token", http.StatusInternalServerError)
+			return
+		}
+
+		if !recordAudit(w, r, db, AuditImpersonate, user.ID, request.Reason) {
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(ImpersonationResponse{
+			AccessToken: accessToken,
+			ExpiresAt:   time.Now().Add(authJWT.ImpersonationTokenDuration),
+		})
+	}
+}
+
+// ListAuditLogs godoc
+// @Summary      List audit logs
+// @Description  List administrator actions, newest first, optionally filtered by actor or target user. Admin only.
+// @Security     BearerAuth
+// @Tags         admin
+// @Produce      json
+// @Param        actor_id query     int     false  "Administrator who performed the action"
+// @Param        user_id  query     int     false  "User the action was performed on"
+// @Param        page     query     int     false  "Page number"
+// @Param        pageSize query     int     false  "Number of entries per page"
+// @Success      200  {array}   AuditLogResponse
+// @Failure      403  {string}  string "Forbidden"
+// @Failure      500  {string}  string "Failed to fetch audit logs"
+// @Router       /api/admin/audit [get]
+func ListAuditLogs(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, pageSize := pagination(r)
+
+		query := db.Model(&db_models.AuditLog{}).Order("id DESC")
+		if actorID, err := strconv.ParseUint(r.URL.Query().Get("actor_id"), 10, 64); err == nil {
+			query = query.Where("actor_id = ?", actorID)
+		}
+		if userID, err := strconv.ParseUint(r.URL.Query().Get("user_id"), 10, 64); err == nil {
+			query = query.Where("target_user_id = ?", userID)
+		}
+
+		var logs []db_models.AuditLog
+		if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+			http.Error(w, "Failed to fetch audit logs", http.StatusInternalServerError)
+			return
+		}
+
+		response := []AuditLogResponse{}
+		for _, entry := range logs {
+			response = append(response, AuditLogResponse{
+				ID:           entry.ID,
+				ActorID:      entry.ActorID,
+				Action:       entry.Action,
+				TargetUserID: entry.TargetUserID,
+				Details:      entry.Details,
+				IP:           entry.IP,
+				CreatedAt:    entry.CreatedAt,
+			})
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// UnlockAccount godoc
+// @Summary      Unlock an account
+// @Description  Clear the failed login counter and lockout of a user. Admin only.
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Param        id      path      int                 true   "User ID"
+// @Param        request body      AdminActionRequest  false  "Reason"
+// @Success      204  "No Content"
+// @Failure      403  {string}  string "Forbidden"
+// @Failure      404  {string}  string "User not found"
+// @Failure      500  {string}  string "Failed to unlock account"
+// @Router       /api/admin/users/{id}/unlock [post]
+func UnlockAccount(db *gorm.DB, tokenStore *authToken.TokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, ok := decodeAdminAction(w, r)
+		if !ok {
+			return
+		}
+
+		user, ok := loadTargetUser(w, r, db)
+		if !ok {
+			return
+		}
+
+		if err := tokenStore.ResetLoginFailures(loginThrottleKey(user)); err != nil {
+			http.Error(w, "Failed to unlock account", http.StatusInternalServerError)
+			return
+		}
+
+		if !recordAudit(w, r, db, AuditUnlockAccount, user.ID, request.Reason) {
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// forceLogout kullanıcının tüm oturumlarını ve access token'larını iptal eder, WebSocket bağlantısını kapatır
+func forceLogout(tokenStore *authToken.TokenStore, userID uint) error {
+	if err := revokeAllUserTokens(tokenStore, userID, ""); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%d", userID)
+	if session, ok := UserSessions.Remove(key); ok {
+		session.Close()
+	}
+	return markUserOffline(context.Background(), key, tokenStore)
+}
+
+// recordAudit işlemi yapan yönetici adına denetim kaydı yazar. Kayıt yazılamazsa 500 döner ve false verir.
+func recordAudit(w http.ResponseWriter, r *http.Request, db *gorm.DB, action string, targetUserID uint, details string) bool {
+	entry := db_models.AuditLog{
+		ActorID:      authz.MustPrincipal(r).UserID,
+		Action:       action,
+		TargetUserID: targetUserID,
+		Details:      details,
+		IP:           clientIP(r),
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		http.Error(w, "Failed to record audit log", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// decodeAdminAction gövdesi boş olabilen yönetici isteğini okur
+func decodeAdminAction(w http.ResponseWriter, r *http.Request) (AdminActionRequest, bool) {
+	var request AdminActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return request, false
+	}
+	return request, true
+}
+
+func loadTargetUser(w http.ResponseWriter, r *http.Request, db *gorm.DB) (db_models.User, bool) {
+	var user db_models.User
+	id, ok := targetUserID(w, r)
+	if !ok {
+		return user, false
+	}
+	if err := db.First(&user, id).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return user, false
+	}
+	return user, true
+}
+
+// targetUserID yoldaki kullanıcı ID'sini okur. Sayı olmayan değer ham SQL olarak sorguya
+// eklenebileceği için First'e verilmeden önce çevrilir, geçersizse 404 döner.
+func targetUserID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func pagination(r *http.Request) (int, int) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	return page, pageSize
+}
+
+func adminUserResponse(user db_models.User) AdminUserResponse {
+	return AdminUserResponse{
+		ID:              user.ID,
+		Name:            user.Name,
+		Email:           user.Email,
+		Role:            string(user.Role),
+		EmailVerified:   user.EmailVerifiedAt != nil,
+		TOTPEnabled:     user.TOTPEnabled,
+		SuspendedAt:     user.SuspendedAt,
+		SuspendedReason: user.SuspendedReason,
+		CreatedAt:       user.CreatedAt,
+	}
+}
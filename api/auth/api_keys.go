@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"net/http"
+	"strings"
+	"svm/auth/authz"
+	authJWT "svm/auth/jwt"
+	authToken "svm/auth/token"
+	"svm/models/db_models"
+	"time"
+)
+
+const (
+	APIKeyPrefix          = "svm_"
+	DefaultAPIKeyLifetime = 90  // gün
+	MaxAPIKeyLifetime     = 365 // gün
+)
+
+// CreateAPIKeyRequest represents the structure for creating an API key
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"` // varsayılan 90, en fazla 365
+}
+
+// APIKeyResponse represents an API key without its secret
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// CreateAPIKeyResponse is returned once when a key is created; the key cannot be shown again
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// CreateAPIKey godoc
+// @Summary      Create an API key
+// @Description  Create a named, scoped, expiring API key for machine clients. The key is only returned in this response; send it in the X-API-Key header.
+// @Security     BearerAuth
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Param        request body CreateAPIKeyRequest true "Key name, scopes and lifetime"
+// @Success      201  {object}  CreateAPIKeyResponse
+// @Failure      400  {string}  string "Invalid scope"
+// @Failure      500  {string}  string "Failed to create API key"
+// @Router       /api/api-keys [post]
+func CreateAPIKey(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var request CreateAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		request.Name = strings.TrimSpace(request.Name)
+		if request.Name == "" {
+			http.Error(w, "Name is required", http.StatusBadRequest)
+			return
+		}
+
+		if len(request.Scopes) == 0 {
+			http.Error(w, "At least one scope is required", http.StatusBadRequest)
+			return
+		}
+		for _, scope := range request.Scopes {
+			if !containsString(authJWT.APIKeyScopes, scope) {
+				http.Error(w, "Invalid scope: "+scope, http.StatusBadRequest)
+				return
+			}
+		}
+
+		if request.ExpiresInDays == 0 {
+			request.ExpiresInDays = DefaultAPIKeyLifetime
+		}
+		if request.ExpiresInDays < 0 || request.ExpiresInDays > MaxAPIKeyLifetime {
+			http.Error(w, "expires_in_days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+
+		secret, _, err := authToken.NewOpaqueToken()
+		if err != nil {
+			http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+			return
+		}
+		key := APIKeyPrefix + secret
+
+		apiKey := db_models.APIKey{
+			UserID:    principal.UserID,
+			Name:      request.Name,
+			Prefix:    key[:len(APIKeyPrefix)+8],
+			KeyHash:   authToken.HashOpaqueToken(key),
+			Scopes:    strings.Join(request.Scopes, " "),
+			ExpiresAt: time.Now().AddDate(0, 0, request.ExpiresInDays),
+		}
+		if err := db.Create(&apiKey).Error; err != nil {
+			http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(CreateAPIKeyResponse{
+			APIKeyResponse: apiKeyResponse(apiKey),
+			Key:            key,
+		})
+	}
+}
+
+// ListAPIKeys godoc
+// @Summary      List API keys
+// @Description  List the active API keys of the authenticated user
+// @Security     BearerAuth
+// @Tags         api-keys
+// @Produce      json
+// @Success      200  {array}   APIKeyResponse
+// @Failure      500  {string}  string "Failed to list API keys"
+// @Router       /api/api-keys [get]
+func ListAPIKeys(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var keys []db_models.APIKey
+		if err := db.Where("user_id = ? AND revoked_at IS NULL", principal.UserID).Order("id").Find(&keys).Error; err != nil {
+			http.Error(w, "Failed to list API keys", http.StatusInternalServerError)
+			return
+		}
+
+		response := []APIKeyResponse{}
+		for _, key := range keys {
+			response = append(response, apiKeyResponse(key))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// RevokeAPIKey godoc
+// @Summary      Revoke an API key
+// @Description  Revoke one of the authenticated user's API keys; it stops working immediately
+// @Security     BearerAuth
+// @Tags         api-keys
+// @Param        id   path      string  true  "API key ID"
+// @Success      204  "No Content"
+// @Failure      404  {string}  string "API key not found"
+// @Router       /api/api-keys/{id} [delete]
+func RevokeAPIKey(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		result := db.Model(&db_models.APIKey{}).
+			Where("id = ? AND user_id = ? AND revoked_at IS NULL", chi.URLParam(r, "id"), principal.UserID).
+			Update("RevokedAt", time.Now())
+		if result.Error != nil || result.RowsAffected == 0 {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func apiKeyResponse(key db_models.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     strings.Fields(key.Scopes),
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
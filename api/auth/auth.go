@@ -6,27 +6,35 @@ import (
 	"fmt"
 	"github.com/olahol/melody"
 	"gorm.io/gorm"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"svm/auth/hashing"
 	authJWT "svm/auth/jwt"
+	"svm/auth/security"
 	authToken "svm/auth/token"
+	smvmmiddleware "svm/middleware"
 	"svm/models/db_models"
 	"time"
 )
 
-var UserSessions = make(map[string]*melody.Session)
+// UserSessions kullanıcıların açık WebSocket bağlantılarıdır
+var UserSessions = NewSessionRegistry()
 
 type LoginRequest struct {
-	Email    string  `json:"email"`
-	Password string  `json:"password"`
-	Lat      float64 `json:"lat"` // Kullanıcının enlem bilgisi
-	Lng      float64 `json:"lng"` // Kullanıcının boylam bilgisi
+	Email      string  `json:"email"`
+	Password   string  `json:"password"`
+	Lat        float64 `json:"lat"`         // Kullanıcının enlem bilgisi
+	Lng        float64 `json:"lng"`         // Kullanıcının boylam bilgisi
+	DeviceName string  `json:"device_name"` // Oturum listesinde gösterilecek cihaz adı
 }
 
 // LoginResponse represents the structure for the login response
 type LoginResponse struct {
-	AccessToken  string       `json:"access_token"`
-	RefreshToken string       `json:"refresh_token"`
+	AccessToken  string       `json:"access_token,omitempty"`  // cookie modunda access cookie varsa boş
+	RefreshToken string       `json:"refresh_token,omitempty"` // cookie modunda boş, token HttpOnly cookie'de
+	SessionID    string       `json:"session_id"`
 	UserResponse UserResponse `json:"user"`
 }
 
@@ -41,14 +49,18 @@ type UserResponse struct {
 
 // Login godoc
 // @Summary      User login
-// @Description  Authenticate user and return access and refresh tokens
+// @Description  Authenticate user and return access and refresh tokens. Users with two-factor authentication get an MFA challenge instead, to be completed at /api/login/mfa. Browser clients can send "X-Session-Mode: cookie" to receive the tokens in HttpOnly cookies instead of the body.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        credentials body LoginRequest true "Login credentials"
 // @Success      200  {object}  LoginResponse
+// @Success      202  {object}  MFAChallengeResponse
 // @Failure      400  {string}  string "Invalid request payload"
 // @Failure      401  {string}  string "Invalid email or password"
+// @Failure      403  {string}  string "Email address is not verified or account is suspended"
+// @Failure      429  {string}  string "Too many failed login attempts"
+// @Header       429  {integer} Retry-After "Seconds to wait before the next attempt"
 // @Router       /api/login [post]
 func Login(db *gorm.DB, tokenStore *authToken.TokenStore, m *melody.Melody) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -58,61 +70,157 @@ func Login(db *gorm.DB, tokenStore *authToken.TokenStore, m *melody.Melody) http
 			return
 		}
 
+		// Hesap veya IP çok fazla hatalı deneme yüzünden bekletiliyorsa şifre hiç kontrol edilmez
+		retryAfter, err := tokenStore.LoginRetryAfter(credentials.Email, clientNetwork(r))
+		if err != nil {
+			http.Error(w, "Failed to check login attempts", http.StatusInternalServerError)
+			return
+		}
+		if retryAfter > 0 {
+			writeTooManyAttempts(w, retryAfter)
+			return
+		}
+
 		var user db_models.User
 
 		// Preload Friends
-		if err := db.Preload("Friends").Preload("Locations").Where("email = ?", credentials.Email).First(&user).Error; err != nil {
-			http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+		if credentials.Email == "" {
+			recordLoginFailure(w, r, tokenStore, 0, credentials.Email)
+			return
+		}
+		if err := db.Preload("Friends").Preload("Locations").Where("LOWER(email) = LOWER(?)", credentials.Email).First(&user).Error; err != nil {
+			// Cevap süresinden adresin kayıtlı olup olmadığı anlaşılmasın
+			hashing.SimulatePasswordCheck(credentials.Password)
+			recordLoginFailure(w, r, tokenStore, 0, credentials.Email)
 			return
 		}
 
+		passwordHash := user.PasswordHash
 		if !hashing.CheckPassword(&user, credentials.Password) {
-			http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+			recordLoginFailure(w, r, tokenStore, user.ID, credentials.Email)
 			return
 		}
 
-		accessToken, err := authJWT.GenerateAccessToken(user.ID, user.Email, user.Name, getUserFriendsAsEmails(user))
-		if err != nil {
-			http.Error(w, "Failed to generate access token", http.StatusInternalServerError)
-			return
+		// Eski algoritma veya parametrelerle oluşturulmuş hash yenilendiyse kaydedilir
+		if user.PasswordHash != passwordHash {
+			if err := db.Model(&user).Update("PasswordHash", user.PasswordHash).Error; err != nil {
+				log.Printf("Failed to upgrade password hash for user %d: %v", user.ID, err)
+			}
 		}
 
-		refreshToken, err := authJWT.GenerateRefreshToken(user.ID)
-		if err != nil {
-			http.Error(w, "Failed to generate refresh token", http.StatusInternalServerError)
+		// İki adımlı doğrulama açıksa token yerine kısa süreli bir MFA challenge döner
+		// Sayaç ikinci adım geçilince sıfırlanır, yoksa doğru şifreyle her seferinde yeni kod denemesi açılabilir
+		if requiresMFA(user) {
+			writeMFAChallenge(w, user.ID, authJWT.FactorPassword)
 			return
 		}
 
-		if err := tokenStore.StoreRefreshToken(user.ID, refreshToken, time.Hour*24*7); err != nil {
-			http.Error(w, "Failed to store refresh token", http.StatusInternalServerError)
-			return
+		if err := tokenStore.ResetLoginFailures(credentials.Email); err != nil {
+			log.Printf("Failed to reset login failures for user %d: %v", user.ID, err)
 		}
 
-		response := LoginResponse{
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-			UserResponse: UserResponse{
-				ID:          user.ID,
-				Name:        user.Name,
-				Email:       user.Email,
-				HomeAddress: user.HomeAddress,
-				Friends:     getUserFriendsAsEmails(user),
-				Locations:   getUserLocationsAsResponse(user),
-			},
-		}
-
-		// Kullanıcıyı online olarak işaretleme
-		if err := markUserOnline(context.Background(), fmt.Sprintf("%d", user.Email), tokenStore); err != nil {
-			http.Error(w, "Failed to mark user online", http.StatusInternalServerError)
+		completeLogin(w, r, tokenStore, m, user, credentials.Lat, credentials.Lng, credentials.DeviceName)
+	}
+}
+
+// writeMFAChallenge ilk adımı geçen kullanıcıya /api/login/mfa için kısa süreli challenge döner
+func writeMFAChallenge(w http.ResponseWriter, userID uint, firstFactor string) {
+	mfaToken, err := authJWT.GenerateMFAChallengeToken(userID, firstFactor)
+	if err != nil {
+		http.Error(w, "Failed to generate MFA challenge", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+	})
+}
+
+// completeLogin kimliği doğrulanmış kullanıcı için token'ları üretir, kullanıcıyı online işaretler,
+// arkadaşlarına bildirim gönderir ve LoginResponse'u yazar. user'ın Friends ve Locations alanları yüklenmiş olmalı.
+func completeLogin(w http.ResponseWriter, r *http.Request, tokenStore *authToken.TokenStore, m *melody.Melody, user db_models.User, lat, lng float64, deviceName string) {
+	if user.SuspendedAt != nil {
+		http.Error(w, "Account is suspended", http.StatusForbidden)
+		return
+	}
+	// Telefonla kayıt olan hesapların e-postası yoktur, numara zaten doğrulanmıştır
+	if RequireVerifiedEmail && user.Email != "" && user.EmailVerifiedAt == nil {
+		http.Error(w, "Email address is not verified", http.StatusForbidden)
+		return
+	}
+
+	refreshToken, err := authJWT.GenerateRefreshToken(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to generate refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	sessionID, err := tokenStore.StoreRefreshToken(user.ID, refreshToken, authJWT.RefreshTokenDuration, sessionInfo(r, deviceName))
+	if err != nil {
+		http.Error(w, "Failed to store refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	accessToken, err := authJWT.GenerateAccessToken(user.ID, user.Email, user.Name, string(user.Role), getUserFriendsAsEmails(user), sessionID)
+	if err != nil {
+		http.Error(w, "Failed to generate access token", http.StatusInternalServerError)
+		return
+	}
+
+	response := LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		SessionID:    sessionID,
+		UserResponse: UserResponse{
+			ID:          user.ID,
+			Name:        user.Name,
+			Email:       user.Email,
+			HomeAddress: user.HomeAddress,
+			Friends:     getUserFriendsAsEmails(user),
+			Locations:   getUserLocationsAsResponse(user),
+		},
+	}
+
+	// Kullanıcıyı online olarak işaretleme
+	if err := markUserOnline(context.Background(), fmt.Sprintf("%d", user.ID), tokenStore); err != nil {
+		http.Error(w, "Failed to mark user online", http.StatusInternalServerError)
+		return
+	}
+
+	// Kullanıcının arkadaşlarına WebSocket mesajı gönderme (konum bilgisi ile birlikte)
+	sendLoginNotificationToFriends(user, lat, lng, m)
+
+	// Şüpheli giriş tespiti bu kayıt üzerinden yapılır
+	event := securityEvent(r, security.EventLogin, user.ID)
+	event.Email = user.Email
+	event.DeviceName = deviceName
+	event.Lat, event.Lng = lat, lng
+	event.Details = "session " + sessionID
+	security.Record(event)
+
+	if smvmmiddleware.WantsCookieSession(r) {
+		if !setSessionCookies(w, refreshToken, accessToken) {
 			return
 		}
+		response.RefreshToken = ""
+		if smvmmiddleware.Cookies.AccessToken {
+			response.AccessToken = ""
+		}
+	}
 
-		// Kullanıcının arkadaşlarına WebSocket mesajı gönderme (konum bilgisi ile birlikte)
-		sendLoginNotificationToFriends(user, credentials.Lat, credentials.Lng, m)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
 
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(response)
+// setSessionCookies token'ları cookie'lere yazar, hata olursa 500 döner
+func setSessionCookies(w http.ResponseWriter, refreshToken, accessToken string) bool {
+	if err := smvmmiddleware.SetSessionCookies(w, refreshToken, accessToken); err != nil {
+		http.Error(w, "Failed to set session cookies", http.StatusInternalServerError)
+		return false
 	}
+	return true
 }
 
 func sendLoginNotificationToFriends(user db_models.User, lat, lng float64, m *melody.Melody) {
@@ -133,7 +241,7 @@ func sendLoginNotificationToFriends(user db_models.User, lat, lng float64, m *me
 
 	// Kullanıcının arkadaşlarının WebSocket bağlantılarına mesaj gönderme
 	for _, friend := range user.Friends {
-		if session, ok := UserSessions[fmt.Sprintf("%d", friend.ID)]; ok {
+		if session, ok := UserSessions.Get(fmt.Sprintf("%d", friend.ID)); ok {
 
 			//set location data to []byte
 			locationData, err := json.Marshal(locationData)
@@ -160,6 +268,86 @@ func markUserOffline(ctx context.Context, userID string, tokenStore *authToken.T
 	return tokenStore.RedisClient.Del(ctx, userID).Err() // Kullanıcıyı online listesinden çıkart
 }
 
+// recordLoginFailure hatalı denemeyi hesap ve IP sayaçlarına işler ve 401 ya da 429 döner.
+// userID, e-posta kayıtlı değilse 0'dır.
+func recordLoginFailure(w http.ResponseWriter, r *http.Request, tokenStore *authToken.TokenStore, userID uint, email string) {
+	if retryAfter := countLoginFailure(r, tokenStore, userID, email, "invalid email or password"); retryAfter > 0 {
+		writeTooManyAttempts(w, retryAfter)
+		return
+	}
+	http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+}
+
+// countLoginFailure hatalı denemeyi hesap ve IP sayaçlarına ve güvenlik olaylarına işler,
+// istemcinin bir sonraki denemeden önce beklemesi gereken süreyi döner
+func countLoginFailure(r *http.Request, tokenStore *authToken.TokenStore, userID uint, email, reason string) time.Duration {
+	ip := clientIP(r)
+	account, client, err := tokenStore.RecordLoginFailure(email, clientNetwork(r))
+	if err != nil {
+		log.Printf("Failed to record login failure for %q: %v", email, err)
+	}
+
+	event := securityEvent(r, security.EventLoginFailed, userID)
+	event.Email = email
+	event.Details = reason
+	security.Record(event)
+
+	if account.LockedOut || client.LockedOut {
+		details := fmt.Sprintf("account locked after %d failed attempts", account.Failures)
+		if client.LockedOut {
+			details = fmt.Sprintf("ip locked after %d failed attempts", client.Failures)
+		}
+		security.Record(security.Event{
+			Type:      security.EventLoginLockout,
+			UserID:    userID,
+			Email:     email,
+			IP:        ip,
+			UserAgent: r.UserAgent(),
+			Details:   details,
+		})
+	}
+
+	retryAfter := account.RetryAfter
+	if client.RetryAfter > retryAfter {
+		retryAfter = client.RetryAfter
+	}
+	return retryAfter
+}
+
+// loginThrottleKey hesap sayacının anahtarıdır. E-postası olmayan (telefonla açılmış) hesaplar kullanıcı ID'si ile sayılır.
+func loginThrottleKey(user db_models.User) string {
+	if user.Email != "" {
+		return user.Email
+	}
+	return fmt.Sprintf("user:%d", user.ID)
+}
+
+func writeTooManyAttempts(w http.ResponseWriter, retryAfter time.Duration) {
+	// Retry-After saniye cinsindendir, yukarı yuvarlanır
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, "Too many failed login attempts", http.StatusTooManyRequests)
+}
+
+// sessionInfo isteği yapan cihazın bilgilerini oturum kaydı için toplar
+func sessionInfo(r *http.Request, deviceName string) authToken.SessionInfo {
+	return authToken.SessionInfo{
+		DeviceName: deviceName,
+		UserAgent:  r.UserAgent(),
+		IP:         clientIP(r),
+	}
+}
+
+// clientIP, main'deki RealIP middleware'inin güvenilen proxy'lerden aldığı istemci adresidir
+func clientIP(r *http.Request) string {
+	return smvmmiddleware.ClientIP(r)
+}
+
+// clientNetwork IP sayaçlarında kullanılır, IPv6 istemciler /64 bloğu ile sayılır
+func clientNetwork(r *http.Request) string {
+	return smvmmiddleware.ClientNetwork(r)
+}
+
 func getUserFriendsAsEmails(user db_models.User) []string {
 	friends := []string{}
 	for _, friend := range user.Friends {
@@ -188,18 +376,19 @@ func getUserLocationsAsResponse(user db_models.User) []interface{} {
 
 // RefreshTokenRequest represents the structure for the refresh token request
 type RefreshTokenRequest struct {
-	UserID       uint   `json:"user_id"`
-	RefreshToken string `json:"refresh_token"`
+	UserID       uint   `json:"user_id"`       // opsiyonel, kullanıcı refresh token'dan alınır
+	RefreshToken string `json:"refresh_token"` // cookie modunda boş bırakılabilir
 }
 
 // RefreshTokenResponse represents the structure for the refresh token response
 type RefreshTokenResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 }
 
 // RefreshToken godoc
 // @Summary      Refresh access token
-// @Description  Generate a new access token using a valid refresh token
+// @Description  Exchange a valid refresh token for a new access token and a new refresh token. The old refresh token is invalidated; presenting it again revokes the whole token family. In cookie mode the refresh token is read from and written back to its HttpOnly cookie, and the X-CSRF-Token header must match the CSRF cookie.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -207,52 +396,87 @@ type RefreshTokenResponse struct {
 // @Success      200  {object}  RefreshTokenResponse
 // @Failure      400  {string}  string "Invalid request payload"
 // @Failure      401  {string}  string "Invalid or expired refresh token"
+// @Failure      403  {string}  string "Account is suspended"
 // @Router       /api/refresh-token [post]
 func RefreshToken(db *gorm.DB, tokenStore *authToken.TokenStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var request RefreshTokenRequest
-		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
 			http.Error(w, "Invalid request payload", http.StatusBadRequest)
 			return
 		}
+		useCookies := smvmmiddleware.WantsCookieSession(r)
+		if request.RefreshToken == "" {
+			request.RefreshToken, useCookies = smvmmiddleware.RefreshTokenFromCookie(r)
+		}
 
-		// Token'ı doğrulama
-		_, err := authJWT.ValidateToken(request.RefreshToken)
-		if err != nil {
+		// Token'ı doğrulama, access token burada kabul edilmez
+		claims, err := authJWT.ValidateToken(request.RefreshToken, authJWT.TokenTypeRefresh)
+		if err != nil || (request.UserID != 0 && claims.UserID != request.UserID) {
 			http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
 			return
 		}
+		userID := claims.UserID
 
-		// Redis'ten refresh token'ı çekme
-		storedUserID, err := tokenStore.FetchRefreshToken(request.UserID, request.RefreshToken) // Sadece token ile arama yapıyoruz
+		// Yeni refresh token oluşturup eskisinin yerine koyma, eski token artık geçersiz
+		newRefreshToken, err := authJWT.GenerateRefreshToken(userID)
 		if err != nil {
-			http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+			http.Error(w, "Failed to generate refresh token", http.StatusInternalServerError)
 			return
 		}
 
-		// Kullanıcı ID'si uyuşmuyor mu? Token'ı sil
-		if storedUserID != request.UserID {
-			_ = tokenStore.DeleteRefreshToken(storedUserID, request.RefreshToken) // Token'ı siliyoruz
-			http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		sessionID, err := tokenStore.RotateRefreshToken(userID, request.RefreshToken, newRefreshToken, authJWT.RefreshTokenDuration, sessionInfo(r, ""))
+		if err != nil {
+			switch err {
+			case authToken.ErrRefreshTokenReused:
+				// Daha önce kullanılmış bir token, tüm aile iptal edildi
+				event := securityEvent(r, security.EventTokenRefresh, userID)
+				event.Details = "refresh token reuse detected, session revoked"
+				security.Record(event)
+				http.Error(w, "Refresh token reuse detected", http.StatusUnauthorized)
+			case authToken.ErrRefreshTokenNotFound:
+				http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+			default:
+				http.Error(w, "Failed to rotate refresh token", http.StatusInternalServerError)
+			}
 			return
 		}
 
 		//User i çekme
 		var user db_models.User
-		if err := db.Where("id = ?", request.UserID).First(&user).Error; err != nil {
+		if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
 			http.Error(w, "User not found", http.StatusNotFound)
 			return
 		}
+		if user.SuspendedAt != nil {
+			http.Error(w, "Account is suspended", http.StatusForbidden)
+			return
+		}
 
 		// Yeni access token oluşturma
-		accessToken, err := authJWT.GenerateAccessToken(request.UserID, user.Email, user.Name, getUserFriendsAsEmails(user))
+		accessToken, err := authJWT.GenerateAccessToken(userID, user.Email, user.Name, string(user.Role), getUserFriendsAsEmails(user), sessionID)
 		if err != nil {
 			http.Error(w, "Failed to generate access token", http.StatusInternalServerError)
 			return
 		}
 
 		response := RefreshTokenResponse{
-			AccessToken: accessToken,
+			AccessToken:  accessToken,
+			RefreshToken: newRefreshToken,
+		}
+
+		event := securityEvent(r, security.EventTokenRefresh, userID)
+		event.Details = "session " + sessionID
+		security.Record(event)
+
+		if useCookies {
+			if !setSessionCookies(w, newRefreshToken, accessToken) {
+				return
+			}
+			response.RefreshToken = ""
+			if smvmmiddleware.Cookies.AccessToken {
+				response.AccessToken = ""
+			}
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -261,8 +485,8 @@ func RefreshToken(db *gorm.DB, tokenStore *authToken.TokenStore) http.HandlerFun
 }
 
 type LogoutRequest struct {
-	UserID       uint   `json:"user_id"`
-	RefreshToken string `json:"refresh_token"`
+	UserID       uint   `json:"user_id"`       // opsiyonel, kullanıcı refresh token'dan alınır
+	RefreshToken string `json:"refresh_token"` // cookie modunda boş bırakılabilir
 }
 
 type LogoutResponse struct {
@@ -271,7 +495,7 @@ type LogoutResponse struct {
 
 // Logout godoc
 // @Summary      User logout
-// @Description  Invalidate user tokens and close WebSocket session
+// @Description  Invalidate user tokens and close WebSocket session. An access token sent as a Bearer token or cookie is revoked immediately, and session cookies are cleared.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -283,28 +507,51 @@ type LogoutResponse struct {
 func Logout(tokenStore *authToken.TokenStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var request LogoutRequest
-		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
 			http.Error(w, "Invalid request payload", http.StatusBadRequest)
 			return
 		}
+		if request.RefreshToken == "" {
+			request.RefreshToken, _ = smvmmiddleware.RefreshTokenFromCookie(r)
+		}
+
+		// Refresh token'ı doğrulama, kullanıcı token'ın kendisinden alınır
+		claims, err := authJWT.ValidateToken(request.RefreshToken, authJWT.TokenTypeRefresh)
+		if err != nil || (request.UserID != 0 && claims.UserID != request.UserID) {
+			http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+			return
+		}
+		userID := claims.UserID
 
-		// Refresh token'ı doğrulama
-		storedUserID, err := tokenStore.FetchRefreshToken(request.UserID, request.RefreshToken)
-		if err != nil || storedUserID != request.UserID {
+		storedUserID, err := tokenStore.FetchRefreshToken(userID, request.RefreshToken)
+		if err != nil || storedUserID != userID {
 			http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
 			return
 		}
 
 		// Refresh token'ı silme
-		if err := tokenStore.DeleteRefreshToken(request.UserID, request.RefreshToken); err != nil {
+		if err := tokenStore.DeleteRefreshToken(userID, request.RefreshToken); err != nil {
 			http.Error(w, "Failed to delete refresh token", http.StatusInternalServerError)
 			return
 		}
 
+		// Access token gönderildiyse süresi dolmadan iptal listesine ekle
+		accessToken, ok := smvmmiddleware.BearerToken(r)
+		if !ok {
+			accessToken, ok = smvmmiddleware.AccessTokenFromCookie(r)
+		}
+		if ok {
+			if accessClaims, err := authJWT.ValidateToken(accessToken, authJWT.TokenTypeAccess); err == nil && accessClaims.UserID == userID {
+				if err := tokenStore.RevokeAccessToken(accessClaims.ID, accessClaims.ExpiresAt.Time); err != nil {
+					http.Error(w, "Failed to revoke access token", http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+
 		// WebSocket oturumunu kapatma
-		if session, ok := UserSessions[fmt.Sprintf("%d", request.UserID)]; ok {
+		if session, ok := UserSessions.Remove(fmt.Sprintf("%d", userID)); ok {
 			session.Close()
-			delete(UserSessions, fmt.Sprintf("%d", request.UserID))
 		}
 
 		response := LogoutResponse{
@@ -312,11 +559,17 @@ func Logout(tokenStore *authToken.TokenStore) http.HandlerFunc {
 		}
 
 		// Kullanıcıyı offline olarak işaretleme
-		if err := markUserOffline(context.Background(), fmt.Sprintf("%d", request.UserID), tokenStore); err != nil {
+		if err := markUserOffline(context.Background(), fmt.Sprintf("%d", userID), tokenStore); err != nil {
 			http.Error(w, "Failed to mark user offline", http.StatusInternalServerError)
 			return
 		}
 
+		if smvmmiddleware.Cookies.Enabled {
+			smvmmiddleware.ClearSessionCookies(w)
+		}
+
+		security.Record(securityEvent(r, security.EventLogout, userID))
+
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(response)
 	}
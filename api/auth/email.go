@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	authJWT "svm/auth/jwt"
+	"svm/auth/verification"
+	"svm/models/db_models"
+	"time"
+)
+
+// RequireVerifiedEmail blocks login for users who have not confirmed their email address
+var RequireVerifiedEmail = false
+
+// ResendVerificationRequest represents the structure for requesting a new verification link
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// MessageResponse is a generic response carrying a human readable message
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// VerifyEmail godoc
+// @Summary      Verify email address
+// @Description  Confirm ownership of an email address with the token from the verification link
+// @Tags         auth
+// @Produce      json
+// @Param        token query     string  true  "Verification token"
+// @Success      200  {object}  MessageResponse
+// @Failure      400  {string}  string "Invalid or expired verification link"
+// @Router       /api/email/verify [get]
+func VerifyEmail(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := authJWT.ValidateToken(r.URL.Query().Get("token"), authJWT.TokenTypeEmailVerification)
+		if err != nil {
+			http.Error(w, "Invalid or expired verification link", http.StatusBadRequest)
+			return
+		}
+
+		// Link yalnızca gönderildiği e-posta adresi hâlâ geçerliyse çalışır
+		var user db_models.User
+		if err := db.Where("id = ? AND email = ?", claims.UserID, claims.Email).First(&user).Error; err != nil {
+			http.Error(w, "Invalid or expired verification link", http.StatusBadRequest)
+			return
+		}
+
+		if user.EmailVerifiedAt == nil {
+			now := time.Now()
+			if err := db.Model(&user).Update("EmailVerifiedAt", &now).Error; err != nil {
+				http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(MessageResponse{Message: "Email address verified"})
+	}
+}
+
+// ResendVerificationEmail godoc
+// @Summary      Resend verification email
+// @Description  Send a new verification link. The response is the same whether or not the address is registered.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body ResendVerificationRequest true "Email address"
+// @Success      202  {object}  MessageResponse
+// @Failure      400  {string}  string "Invalid request payload"
+// @Router       /api/email/verify/resend [post]
+func ResendVerificationEmail(db *gorm.DB, sender *verification.Sender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request ResendVerificationRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		var user db_models.User
+		if err := db.Where("LOWER(email) = LOWER(?) AND email_verified_at IS NULL", request.Email).First(&user).Error; err == nil && request.Email != "" {
+			if err := sender.Send(user); err != nil {
+				log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+			}
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(MessageResponse{Message: "If the address is registered and unverified, a verification link has been sent"})
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"svm/auth/authz"
+	"svm/auth/hashing"
+	"svm/auth/identity"
+	"svm/auth/oidc"
+	"svm/auth/policy"
+	"svm/auth/security"
+	authToken "svm/auth/token"
+	"svm/models/db_models"
+	"time"
+)
+
+// IdentityResponse represents one sign-in method of a user
+type IdentityResponse struct {
+	ID        uint                   `json:"id"`
+	Type      db_models.IdentityType `json:"type"`
+	Issuer    string                 `json:"issuer,omitempty"`
+	Name      string                 `json:"name,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// LinkPasswordRequest represents the structure for adding a password to an account that has none
+type LinkPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// OIDCLinkRequest represents the parameters the identity provider redirected back with when linking
+type OIDCLinkRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// ListIdentities godoc
+// @Summary      List sign-in methods
+// @Description  List the identities (password, OpenID Connect accounts, passkeys, phone numbers) the authenticated user can sign in with
+// @Security     BearerAuth
+// @Tags         identities
+// @Produce      json
+// @Success      200  {array}   IdentityResponse
+// @Failure      500  {string}  string "Failed to list identities"
+// @Router       /api/identities [get]
+func ListIdentities(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		identities, err := identity.List(db, principal.UserID)
+		if err != nil {
+			http.Error(w, "Failed to list identities", http.StatusInternalServerError)
+			return
+		}
+
+		response := []IdentityResponse{}
+		for _, i := range identities {
+			response = append(response, identityResponse(i))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// LinkPassword godoc
+// @Summary      Add a password
+// @Description  Set a password on an account that has none, e.g. one created through an identity provider. Use /api/password/change to change an existing password.
+// @Security     BearerAuth
+// @Tags         identities
+// @Accept       json
+// @Produce      json
+// @Param        request body LinkPasswordRequest true "New password"
+// @Success      201  {object}  IdentityResponse
+// @Failure      400  {object}  policy.ValidationError "Password does not meet the policy"
+// @Failure      409  {string}  string "Account already has a password"
+// @Failure      500  {string}  string "Failed to link password"
+// @Router       /api/identities/password [post]
+func LinkPassword(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var request LinkPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		var user db_models.User
+		if err := db.First(&user, principal.UserID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if user.PasswordHash != "" {
+			http.Error(w, "Account already has a password", http.StatusConflict)
+			return
+		}
+
+		if err := policy.DefaultPolicy.Check(request.Password, user.Name, user.Email); err != nil {
+			writePasswordPolicyError(w, err)
+			return
+		}
+		if err := hashing.SetPassword(&user, request.Password); err != nil {
+			http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+			return
+		}
+
+		var linked db_models.Identity
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Update("PasswordHash", user.PasswordHash).Error; err != nil {
+				return err
+			}
+			var err error
+			linked, err = identity.Link(tx, user.ID, db_models.IdentityPassword, "", identity.PasswordSubject(user.ID), "")
+			return err
+		})
+		if err != nil {
+			http.Error(w, "Failed to link password", http.StatusInternalServerError)
+			return
+		}
+
+		event := securityEvent(r, security.EventPasswordChanged, user.ID)
+		event.Details = "password added"
+		security.Record(event)
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(identityResponse(linked))
+	}
+}
+
+// BeginOIDCLink godoc
+// @Summary      Start linking an identity provider account
+// @Description  Start an authorization code flow with PKCE that links the provider account to the authenticated user. Send the browser to authorization_url; the provider redirects back with code and state for /api/identities/oidc/finish.
+// @Security     BearerAuth
+// @Tags         identities
+// @Produce      json
+// @Success      200  {object}  OIDCBeginResponse
+// @Failure      500  {string}  string "Failed to start login"
+// @Router       /api/identities/oidc/begin [post]
+func BeginOIDCLink(tokenStore *authToken.TokenStore, provider *oidc.Provider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+		beginOIDCFlow(w, tokenStore, provider, principal.UserID)
+	}
+}
+
+// FinishOIDCLink godoc
+// @Summary      Finish linking an identity provider account
+// @Description  Exchange the authorization code for an ID token and link the provider account to the authenticated user
+// @Security     BearerAuth
+// @Tags         identities
+// @Accept       json
+// @Produce      json
+// @Param        request body OIDCLinkRequest true "Authorization code and state"
+// @Success      201  {object}  IdentityResponse
+// @Failure      400  {string}  string "Invalid or expired login state"
+// @Failure      401  {string}  string "Login with the identity provider failed"
+// @Failure      409  {string}  string "Account is already linked to another user"
+// @Router       /api/identities/oidc/finish [post]
+func FinishOIDCLink(db *gorm.DB, tokenStore *authToken.TokenStore, provider *oidc.Provider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var request OIDCLinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		// Sadece bu kullanıcının başlattığı bağlama akışı kabul edilir
+		claims, ok := finishOIDCFlow(w, r, tokenStore, provider, request.Code, request.State, principal.UserID)
+		if !ok {
+			return
+		}
+
+		linked, err := identity.Link(db, principal.UserID, db_models.IdentityOIDC, claims.Issuer, claims.Subject, claims.Email)
+		if err != nil {
+			if err == identity.ErrAlreadyLinked {
+				http.Error(w, "Account is already linked to another user", http.StatusConflict)
+			} else {
+				http.Error(w, "Failed to link account", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(identityResponse(linked))
+	}
+}
+
+// UnlinkIdentity godoc
+// @Summary      Remove a sign-in method
+// @Description  Unlink one of the authenticated user's identities. Removing the password clears it and removing a passkey deletes it. The last remaining sign-in method can't be removed.
+// @Security     BearerAuth
+// @Tags         identities
+// @Param        id   path      string  true  "Identity ID"
+// @Success      204  "No Content"
+// @Failure      404  {string}  string "Identity not found"
+// @Failure      409  {string}  string "Can't remove the last sign-in method"
+// @Router       /api/identities/{id} [delete]
+func UnlinkIdentity(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Identity not found", http.StatusNotFound)
+			return
+		}
+
+		unlinked, err := identity.Unlink(db, principal.UserID, uint(id))
+		if err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				http.Error(w, "Identity not found", http.StatusNotFound)
+			case err == identity.ErrLastIdentity:
+				http.Error(w, "Can't remove the last sign-in method", http.StatusConflict)
+			default:
+				http.Error(w, "Failed to unlink identity", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		// Parolanın ya da SMS ile ikinci adımın kaldırılması güvenlik geçmişinde görünmeli
+		switch unlinked.Identity.Type {
+		case db_models.IdentityPassword:
+			event := securityEvent(r, security.EventPasswordChanged, principal.UserID)
+			event.Details = "password removed"
+			security.Record(event)
+		case db_models.IdentityPhone:
+			if unlinked.SMSMFADisabled {
+				event := securityEvent(r, security.EventMFADisabled, principal.UserID)
+				event.Details = "sms, phone removed"
+				security.Record(event)
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func identityResponse(i db_models.Identity) IdentityResponse {
+	return IdentityResponse{
+		ID:        i.ID,
+		Type:      i.Type,
+		Issuer:    i.Issuer,
+		Name:      i.Name,
+		CreatedAt: i.CreatedAt,
+	}
+}
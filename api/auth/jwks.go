@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	authJWT "svm/auth/jwt"
+)
+
+// JWKS godoc
+// @Summary      JSON Web Key Set
+// @Description  Public keys that verify access and refresh tokens, including keys kept during a rotation window
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  authJWT.JWKSet
+// @Router       /.well-known/jwks.json [get]
+func JWKS(keyManager *authJWT.KeyManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Diğer servisler anahtarları önbelleğe alabilir, rotasyon penceresi bundan uzun olmalı
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(keyManager.JWKS())
+	}
+}
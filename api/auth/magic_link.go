@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/olahol/melody"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"net/url"
+	authJWT "svm/auth/jwt"
+	authToken "svm/auth/token"
+	"svm/mail"
+	"svm/models/db_models"
+	"time"
+)
+
+// MagicLinkRequest represents the structure for requesting a passwordless login link
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// MagicLinkResponse carries the secret that binds the emailed link to the requesting device
+type MagicLinkResponse struct {
+	Message     string `json:"message"`
+	DeviceToken string `json:"device_token"` // link kullanılırken aynı cihazdan geri gönderilmeli
+}
+
+// MagicLinkLoginRequest represents the structure for redeeming a login link
+type MagicLinkLoginRequest struct {
+	Token       string  `json:"token"`
+	DeviceToken string  `json:"device_token"`
+	Lat         float64 `json:"lat"`
+	Lng         float64 `json:"lng"`
+	DeviceName  string  `json:"device_name"`
+}
+
+// RequestMagicLink godoc
+// @Summary      Request a login link
+// @Description  Email a single-use, short-lived login link. The returned device_token must be sent back together with the link's token, so the link only works on the device that asked for it. The response is the same whether or not the address is registered.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body MagicLinkRequest true "Email address"
+// @Success      202  {object}  MagicLinkResponse
+// @Failure      400  {string}  string "Invalid request payload"
+// @Failure      500  {string}  string "Failed to create device token"
+// @Router       /api/login/magic [post]
+func RequestMagicLink(db *gorm.DB, tokenStore *authToken.TokenStore, mailer mail.Mailer, loginURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request MagicLinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		// Cihaz token'ı her istekte üretilir, böylece hesabın var olup olmadığı anlaşılmaz
+		deviceToken, deviceHash, err := authToken.NewOpaqueToken()
+		if err != nil {
+			http.Error(w, "Failed to create device token", http.StatusInternalServerError)
+			return
+		}
+
+		// Link arka planda gönderilir, cevap süresi hesabın var olup olmadığına göre değişmesin
+		var user db_models.User
+		if err := db.Where("LOWER(email) = LOWER(?)", request.Email).First(&user).Error; err == nil && request.Email != "" && user.SuspendedAt == nil {
+			go func() {
+				if err := sendMagicLinkEmail(tokenStore, mailer, loginURL, user, deviceHash); err != nil {
+					log.Printf("Failed to send login link to user %d: %v", user.ID, err)
+				}
+			}()
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(MagicLinkResponse{
+			Message:     "If the address is registered, a login link has been sent",
+			DeviceToken: deviceToken,
+		})
+	}
+}
+
+// LoginWithMagicLink godoc
+// @Summary      Log in with a login link
+// @Description  Redeem a login link from the device that requested it. Each link works once. Users with two-factor authentication get an MFA challenge instead, to be completed at /api/login/mfa.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body MagicLinkLoginRequest true "Link token and device token"
+// @Success      200  {object}  LoginResponse
+// @Success      202  {object}  MFAChallengeResponse
+// @Failure      400  {string}  string "Invalid request payload"
+// @Failure      401  {string}  string "Invalid or expired login link"
+// @Failure      403  {string}  string "Account is suspended"
+// @Router       /api/login/magic/verify [post]
+func LoginWithMagicLink(db *gorm.DB, tokenStore *authToken.TokenStore, m *melody.Melody) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request MagicLinkLoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		claims, err := authJWT.ValidateToken(request.Token, authJWT.TokenTypeMagicLink)
+		if err != nil {
+			http.Error(w, "Invalid or expired login link", http.StatusUnauthorized)
+			return
+		}
+
+		// Link başka bir cihazda açıldıysa harcanmaz, asıl cihaz hâlâ kullanabilir
+		deviceHash := authToken.HashOpaqueToken(request.DeviceToken)
+		if request.DeviceToken == "" || subtle.ConstantTimeCompare([]byte(deviceHash), []byte(claims.DeviceHash)) != 1 {
+			http.Error(w, "Invalid or expired login link", http.StatusUnauthorized)
+			return
+		}
+
+		// Tekrar kullanımı engellemek için link burada tüketilir
+		userID, err := tokenStore.ConsumeMagicLink(claims.ID)
+		if err != nil {
+			if err == authToken.ErrMagicLinkNotFound {
+				http.Error(w, "Invalid or expired login link", http.StatusUnauthorized)
+			} else {
+				http.Error(w, "Failed to redeem login link", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		// E-posta adresi link gönderildikten sonra değiştiyse link geçersizdir
+		var user db_models.User
+		if err := db.Preload("Friends").Preload("Locations").First(&user, userID).Error; err != nil || userID != claims.UserID || user.Email != claims.Email {
+			http.Error(w, "Invalid or expired login link", http.StatusUnauthorized)
+			return
+		}
+
+		// Link e-postaya ulaşıldığını kanıtlar, adres doğrulanmamışsa doğrulanmış sayılır
+		if user.EmailVerifiedAt == nil {
+			now := time.Now()
+			if err := db.Model(&user).Update("EmailVerifiedAt", &now).Error; err != nil {
+				http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// Link tek başına ikinci adımın yerini tutmaz
+		if requiresMFA(user) {
+			writeMFAChallenge(w, user.ID, authJWT.FactorMagicLink)
+			return
+		}
+
+		completeLogin(w, r, tokenStore, m, user, request.Lat, request.Lng, request.DeviceName)
+	}
+}
+
+func sendMagicLinkEmail(tokenStore *authToken.TokenStore, mailer mail.Mailer, loginURL string, user db_models.User, deviceHash string) error {
+	token, jti, err := authJWT.GenerateMagicLinkToken(user.ID, user.Email, deviceHash)
+	if err != nil {
+		return err
+	}
+	if err := tokenStore.StoreMagicLink(user.ID, jti, authJWT.MagicLinkTokenDuration); err != nil {
+		return err
+	}
+
+	link := loginURL + "?token=" + url.QueryEscape(token)
+	return mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below on the device where you asked for it to log in:\n\n%s\n\nThe link expires in %s and can be used once. If you didn't ask for this, you can ignore this email.\n",
+			user.Name, link, authJWT.MagicLinkTokenDuration),
+	})
+}
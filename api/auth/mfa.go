@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/olahol/melody"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"strings"
+	"svm/auth/authz"
+	"svm/auth/hashing"
+	authJWT "svm/auth/jwt"
+	"svm/auth/mfa"
+	"svm/auth/security"
+	authToken "svm/auth/token"
+	"svm/models/db_models"
+	"time"
+)
+
+// MFAChallengeResponse is returned by Login when the user has two-factor authentication enabled
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+// LoginMFARequest represents the second step of a two-step login
+type LoginMFARequest struct {
+	MFAToken   string  `json:"mfa_token"`
+	Code       string  `json:"code"` // TOTP kodu, kurtarma kodu veya SMS kodu
+	Lat        float64 `json:"lat"`
+	Lng        float64 `json:"lng"`
+	DeviceName string  `json:"device_name"`
+}
+
+// TOTPEnrollResponse carries the secret to add to an authenticator app
+type TOTPEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// TOTPVerifyRequest represents the structure for confirming TOTP enrollment
+type TOTPVerifyRequest struct {
+	Code string `json:"code"`
+}
+
+// TOTPVerifyResponse carries the one-time recovery codes, shown only once
+type TOTPVerifyResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TOTPDisableRequest represents the structure for turning two-factor authentication off
+type TOTPDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// LoginMFA godoc
+// @Summary      Complete two-step login
+// @Description  Exchange the MFA challenge token from /api/login and a TOTP, recovery or SMS code (see /api/login/mfa/sms) for access and refresh tokens. Logins that started with an SMS code only accept a TOTP or recovery code.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body LoginMFARequest true "MFA challenge and code"
+// @Success      200  {object}  LoginResponse
+// @Failure      400  {string}  string "Invalid request payload"
+// @Failure      401  {string}  string "Invalid verification code"
+// @Failure      429  {string}  string "Too many failed login attempts"
+// @Header       429  {integer} Retry-After "Seconds to wait before the next attempt"
+// @Router       /api/login/mfa [post]
+func LoginMFA(db *gorm.DB, tokenStore *authToken.TokenStore, m *melody.Melody) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request LoginMFARequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		claims, err := authJWT.ValidateToken(request.MFAToken, authJWT.TokenTypeMFA)
+		if err != nil {
+			http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+			return
+		}
+
+		// Aynı challenge ile sınırsız kod denenmesini engelle
+		if err := tokenStore.RecordMFAAttempt(claims.ID, authJWT.MFATokenDuration); err != nil {
+			if err == authToken.ErrMFAChallengeUsed {
+				http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+			} else {
+				http.Error(w, "Failed to record MFA attempt", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		var user db_models.User
+		if err := db.Preload("Friends").Preload("Locations").First(&user, claims.UserID).Error; err != nil || !requiresMFA(user) {
+			http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+			return
+		}
+		// Telefonla başlayan girişte SMS ikinci adım sayılmaz, yoksa SIM'i ele geçiren iki adımı da geçerdi
+		allowSMS := claims.FirstFactor != authJWT.FactorSMS
+		if !allowSMS && !user.TOTPEnabled {
+			http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+			return
+		}
+
+		// Yeni challenge'lar alınarak kod tahmin edilemesin, hatalı kodlar hesap sayacına da işlenir
+		throttleKey := loginThrottleKey(user)
+		retryAfter, err := tokenStore.LoginRetryAfter(throttleKey, clientNetwork(r))
+		if err != nil {
+			http.Error(w, "Failed to check login attempts", http.StatusInternalServerError)
+			return
+		}
+		if retryAfter > 0 {
+			writeTooManyAttempts(w, retryAfter)
+			return
+		}
+
+		ok, err := verifyLoginSecondFactor(db, tokenStore, &user, request.Code, allowSMS)
+		if err != nil {
+			http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			if retryAfter := countLoginFailure(r, tokenStore, user.ID, throttleKey, "invalid second factor"); retryAfter > 0 {
+				writeTooManyAttempts(w, retryAfter)
+				return
+			}
+			http.Error(w, "Invalid verification code", http.StatusUnauthorized)
+			return
+		}
+
+		if err := tokenStore.CompleteMFAChallenge(claims.ID, authJWT.MFATokenDuration); err != nil {
+			http.Error(w, "Failed to complete MFA challenge", http.StatusInternalServerError)
+			return
+		}
+		if err := tokenStore.ResetLoginFailures(throttleKey); err != nil {
+			log.Printf("Failed to reset login failures for user %d: %v", user.ID, err)
+		}
+
+		completeLogin(w, r, tokenStore, m, user, request.Lat, request.Lng, request.DeviceName)
+	}
+}
+
+// EnrollTOTP godoc
+// @Summary      Start TOTP enrollment
+// @Description  Generate a TOTP secret and an otpauth:// URI to show as a QR code. Two-factor authentication is enabled only after /api/mfa/totp/verify.
+// @Security     BearerAuth
+// @Tags         mfa
+// @Produce      json
+// @Success      200  {object}  TOTPEnrollResponse
+// @Failure      409  {string}  string "Two-factor authentication is already enabled"
+// @Failure      500  {string}  string "Failed to enroll TOTP"
+// @Router       /api/mfa/totp/enroll [post]
+func EnrollTOTP(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var user db_models.User
+		if err := db.First(&user, principal.UserID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if user.TOTPEnabled {
+			http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+
+		secret, err := mfa.GenerateSecret()
+		if err != nil {
+			http.Error(w, "Failed to enroll TOTP", http.StatusInternalServerError)
+			return
+		}
+
+		// Doğrulanana kadar secret beklemede kalır, login akışı etkilenmez
+		if err := db.Model(&user).Update("TOTPSecret", secret).Error; err != nil {
+			http.Error(w, "Failed to enroll TOTP", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(TOTPEnrollResponse{
+			Secret:          secret,
+			ProvisioningURI: mfa.ProvisioningURI(secret, user.Email),
+		})
+	}
+}
+
+// VerifyTOTP godoc
+// @Summary      Confirm TOTP enrollment
+// @Description  Verify a code from the authenticator app, enable two-factor authentication and return one-time recovery codes
+// @Security     BearerAuth
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        request body TOTPVerifyRequest true "Code from the authenticator app"
+// @Success      200  {object}  TOTPVerifyResponse
+// @Failure      400  {string}  string "Invalid request payload"
+// @Failure      401  {string}  string "Invalid verification code"
+// @Failure      409  {string}  string "TOTP enrollment not started"
+// @Router       /api/mfa/totp/verify [post]
+func VerifyTOTP(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var request TOTPVerifyRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		var user db_models.User
+		if err := db.First(&user, principal.UserID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if user.TOTPEnabled || user.TOTPSecret == "" {
+			http.Error(w, "TOTP enrollment not started", http.StatusConflict)
+			return
+		}
+
+		step, ok := mfa.ValidateTOTP(user.TOTPSecret, strings.TrimSpace(request.Code), time.Now())
+		if !ok {
+			http.Error(w, "Invalid verification code", http.StatusUnauthorized)
+			return
+		}
+
+		codes, err := mfa.GenerateRecoveryCodes()
+		if err != nil {
+			http.Error(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Updates(map[string]interface{}{
+				"TOTPEnabled":  true,
+				"TOTPLastStep": step,
+			}).Error; err != nil {
+				return err
+			}
+			return replaceRecoveryCodes(tx, user.ID, codes)
+		})
+		if err != nil {
+			http.Error(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+
+		event := securityEvent(r, security.EventMFAEnabled, user.ID)
+		event.Details = "totp"
+		security.Record(event)
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(TOTPVerifyResponse{RecoveryCodes: codes})
+	}
+}
+
+// DisableTOTP godoc
+// @Summary      Disable TOTP
+// @Description  Turn two-factor authentication off. Requires the current password and a TOTP or recovery code.
+// @Security     BearerAuth
+// @Tags         mfa
+// @Accept       json
+// @Param        request body TOTPDisableRequest true "Password and code"
+// @Success      204  "No Content"
+// @Failure      400  {string}  string "Invalid request payload"
+// @Failure      401  {string}  string "Invalid password or verification code"
+// @Router       /api/mfa/totp/disable [post]
+func DisableTOTP(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var request TOTPDisableRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		var user db_models.User
+		if err := db.First(&user, principal.UserID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if !user.TOTPEnabled || !hashing.CheckPassword(&user, request.Password) {
+			http.Error(w, "Invalid password or verification code", http.StatusUnauthorized)
+			return
+		}
+
+		ok, err := verifySecondFactor(db, &user, request.Code)
+		if err != nil {
+			http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Error(w, "Invalid password or verification code", http.StatusUnauthorized)
+			return
+		}
+
+		if err := disableTOTP(db, user.ID); err != nil {
+			http.Error(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+
+		event := securityEvent(r, security.EventMFADisabled, user.ID)
+		event.Details = "totp"
+		security.Record(event)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// verifySecondFactor accepts a TOTP code that was not used before or an unused recovery code
+func verifySecondFactor(db *gorm.DB, user *db_models.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if step, ok := mfa.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		// Aynı kodun ikinci kez kullanılmasını engellemek için son adım koşullu güncellenir
+		result := db.Model(&db_models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		return result.RowsAffected == 1, result.Error
+	}
+
+	now := time.Now()
+	result := db.Model(&db_models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, mfa.HashRecoveryCode(code)).
+		Update("used_at", &now)
+	return result.RowsAffected == 1, result.Error
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codes []string) error {
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&db_models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	recoveryCodes := make([]db_models.RecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		recoveryCodes = append(recoveryCodes, db_models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: mfa.HashRecoveryCode(code),
+		})
+	}
+	return tx.Create(&recoveryCodes).Error
+}
+
+// verifyLoginSecondFactor allowSMS ise önce SMS ile gönderilen kodu, sonra TOTP veya kurtarma kodunu dener
+func verifyLoginSecondFactor(db *gorm.DB, tokenStore *authToken.TokenStore, user *db_models.User, code string, allowSMS bool) (bool, error) {
+	if allowSMS && user.SMSMFAEnabled && user.Phone != "" {
+		err := tokenStore.VerifySMSOTP(authToken.SMSOTPMFA, user.Phone, authToken.HashOpaqueToken(strings.TrimSpace(code)))
+		if err == nil {
+			return true, nil
+		}
+		if err != authToken.ErrSMSOTPInvalid {
+			return false, err
+		}
+	}
+	if !user.TOTPEnabled {
+		return false, nil
+	}
+	return verifySecondFactor(db, user, code)
+}
+
+// disableTOTP kullanıcının TOTP secret'ını ve kurtarma kodlarını siler
+func disableTOTP(db *gorm.DB, userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&db_models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"TOTPSecret":   "",
+			"TOTPEnabled":  false,
+			"TOTPLastStep": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", userID).Delete(&db_models.RecoveryCode{}).Error
+	})
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/olahol/melody"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"strings"
+	"svm/auth/identity"
+	authJWT "svm/auth/jwt"
+	"svm/auth/oidc"
+	authToken "svm/auth/token"
+	"svm/models/db_models"
+	"time"
+)
+
+// OIDCBeginResponse carries the identity provider URL to send the browser to
+type OIDCBeginResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"` // istemci geri dönüşteki state ile karşılaştırmalı
+}
+
+// OIDCLoginRequest represents the parameters the identity provider redirected back with
+type OIDCLoginRequest struct {
+	Code       string  `json:"code"`
+	State      string  `json:"state"`
+	Lat        float64 `json:"lat"`
+	Lng        float64 `json:"lng"`
+	DeviceName string  `json:"device_name"`
+}
+
+// BeginOIDCLogin godoc
+// @Summary      Start federated login
+// @Description  Start an authorization code login with PKCE at the configured OpenID Connect provider. Send the browser to authorization_url; the provider redirects back with code and state for /api/oidc/login/finish.
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  OIDCBeginResponse
+// @Failure      500  {string}  string "Failed to start login"
+// @Router       /api/oidc/login/begin [post]
+func BeginOIDCLogin(tokenStore *authToken.TokenStore, provider *oidc.Provider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		beginOIDCFlow(w, tokenStore, provider, 0)
+	}
+}
+
+// FinishOIDCLogin godoc
+// @Summary      Finish federated login
+// @Description  Exchange the authorization code for an ID token and log in. The account is found by its linked identity, otherwise matched by the provider's verified email address and linked if the account's address is verified too; an account is created on first login. Users with two-factor authentication get an MFA challenge instead, to be completed at /api/login/mfa.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body OIDCLoginRequest true "Authorization code and state"
+// @Success      200  {object}  LoginResponse
+// @Success      202  {object}  MFAChallengeResponse
+// @Failure      400  {string}  string "Invalid or expired login state"
+// @Failure      401  {string}  string "Login with the identity provider failed"
+// @Failure      403  {string}  string "Email address is not verified by the identity provider"
+// @Failure      409  {string}  string "An account with this email address exists but its address is not verified"
+// @Router       /api/oidc/login/finish [post]
+func FinishOIDCLogin(db *gorm.DB, tokenStore *authToken.TokenStore, m *melody.Melody, provider *oidc.Provider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request OIDCLoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		claims, ok := finishOIDCFlow(w, r, tokenStore, provider, request.Code, request.State, 0)
+		if !ok {
+			return
+		}
+
+		userID, err := oidc.SignIn(oidcAccounts{db: db}, claims)
+		if err != nil {
+			switch err {
+			case oidc.ErrEmailNotVerified:
+				http.Error(w, "Email address is not verified by the identity provider", http.StatusForbidden)
+			case oidc.ErrAccountNotVerified:
+				http.Error(w, "An account with this email address exists; verify the address or sign in and link the provider from the account", http.StatusConflict)
+			default:
+				http.Error(w, "Failed to load user", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		var user db_models.User
+		if err := db.Preload("Friends").Preload("Locations").First(&user, userID).Error; err != nil {
+			http.Error(w, "Failed to load user", http.StatusInternalServerError)
+			return
+		}
+
+		if requiresMFA(user) {
+			writeMFAChallenge(w, user.ID, authJWT.FactorOIDC)
+			return
+		}
+
+		completeLogin(w, r, tokenStore, m, user, request.Lat, request.Lng, request.DeviceName)
+	}
+}
+
+// oidcAccounts, federe girişlerin kullanıcılarını veritabanında bulur ve oluşturur
+type oidcAccounts struct {
+	db *gorm.DB
+}
+
+func (accounts oidcAccounts) FindLinked(issuer, subject string) (uint, error) {
+	linked, err := identity.Find(accounts.db, db_models.IdentityOIDC, issuer, subject)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, oidc.ErrNoAccount
+	}
+	return linked.UserID, err
+}
+
+func (accounts oidcAccounts) FindByEmail(email string) (uint, bool, error) {
+	var user db_models.User
+	err := accounts.db.Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, oidc.ErrNoAccount
+	}
+	return user.ID, user.EmailVerifiedAt != nil, err
+}
+
+func (accounts oidcAccounts) Link(userID uint, claims *oidc.IDTokenClaims) error {
+	_, err := identity.Link(accounts.db, userID, db_models.IdentityOIDC, claims.Issuer, claims.Subject, claims.Email)
+	return err
+}
+
+func (accounts oidcAccounts) Create(claims *oidc.IDTokenClaims) (uint, error) {
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+
+	// Şifresi olmayan hesap, sadece sağlayıcı üzerinden giriş yapabilir
+	now := time.Now()
+	user := db_models.User{
+		Name:            name,
+		Email:           identity.NormalizeEmail(claims.Email),
+		EmailVerifiedAt: &now,
+		Role:            db_models.RoleUser,
+	}
+	err := accounts.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		_, err := identity.Link(tx, user.ID, db_models.IdentityOIDC, claims.Issuer, claims.Subject, claims.Email)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("Created user %d from OIDC subject %s at %s", user.ID, claims.Subject, claims.Issuer)
+	return user.ID, nil
+}
+
+// beginOIDCFlow sağlayıcı adresini döner. linkUserID sıfır değilse akış giriş için değil,
+// kimliği o kullanıcıya bağlamak içindir.
+func beginOIDCFlow(w http.ResponseWriter, tokenStore *authToken.TokenStore, provider *oidc.Provider, linkUserID uint) {
+	authorizationURL, state, err := provider.BeginLogin(tokenStore, linkUserID)
+	if err != nil {
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(OIDCBeginResponse{
+		AuthorizationURL: authorizationURL,
+		State:            state,
+	})
+}
+
+// finishOIDCFlow kodu token ile değiştirir ve ID token'ı doğrular. State başka bir akış
+// (giriş veya başka kullanıcının bağlama isteği) için açıldıysa reddedilir.
+func finishOIDCFlow(w http.ResponseWriter, r *http.Request, tokenStore *authToken.TokenStore, provider *oidc.Provider, code, state string, linkUserID uint) (*oidc.IDTokenClaims, bool) {
+	claims, err := provider.FinishLogin(r.Context(), tokenStore, code, state, linkUserID)
+	if err != nil {
+		if err == oidc.ErrInvalidState {
+			http.Error(w, "Invalid or expired login state", http.StatusBadRequest)
+		} else {
+			log.Printf("OIDC login failed: %v", err)
+			http.Error(w, "Login with the identity provider failed", http.StatusUnauthorized)
+		}
+		return nil, false
+	}
+	return claims, true
+}
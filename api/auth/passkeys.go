@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/olahol/melody"
+	"gorm.io/gorm"
+	"net/http"
+	"svm/auth/authz"
+	"svm/auth/identity"
+	"svm/auth/passkey"
+	authToken "svm/auth/token"
+	"svm/models/db_models"
+	"time"
+)
+
+// PasskeyBeginResponse carries the options for navigator.credentials.create() or .get()
+type PasskeyBeginResponse struct {
+	SessionID string      `json:"session_id"`
+	Options   interface{} `json:"options"`
+}
+
+// PasskeyRegisterRequest represents the result of navigator.credentials.create()
+type PasskeyRegisterRequest struct {
+	SessionID  string          `json:"session_id"`
+	Name       string          `json:"name"`
+	Credential json.RawMessage `json:"credential" swaggertype:"object"`
+}
+
+// PasskeyLoginRequest represents the result of navigator.credentials.get()
+type PasskeyLoginRequest struct {
+	SessionID  string          `json:"session_id"`
+	Credential json.RawMessage `json:"credential" swaggertype:"object"`
+	Lat        float64         `json:"lat"`
+	Lng        float64         `json:"lng"`
+	DeviceName string          `json:"device_name"`
+}
+
+// PasskeyResponse represents a registered passkey
+type PasskeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// BeginPasskeyRegistration godoc
+// @Summary      Start passkey registration
+// @Description  Create WebAuthn registration options for the authenticated user
+// @Security     BearerAuth
+// @Tags         passkeys
+// @Produce      json
+// @Success      200  {object}  PasskeyBeginResponse
+// @Failure      500  {string}  string "Failed to start passkey registration"
+// @Router       /api/passkeys/register/begin [post]
+func BeginPasskeyRegistration(db *gorm.DB, tokenStore *authToken.TokenStore, wa *webauthn.WebAuthn) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		user, err := loadPasskeyUser(db, principal.UserID)
+		if err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		options, session, err := wa.BeginRegistration(user, webauthn.WithExclusions(user.Exclusions()))
+		if err != nil {
+			http.Error(w, "Failed to start passkey registration", http.StatusInternalServerError)
+			return
+		}
+
+		sessionID, err := storePasskeyCeremony(tokenStore, session)
+		if err != nil {
+			http.Error(w, "Failed to start passkey registration", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(PasskeyBeginResponse{SessionID: sessionID, Options: options})
+	}
+}
+
+// FinishPasskeyRegistration godoc
+// @Summary      Finish passkey registration
+// @Description  Verify the authenticator's attestation and store the new passkey
+// @Security     BearerAuth
+// @Tags         passkeys
+// @Accept       json
+// @Produce      json
+// @Param        request body PasskeyRegisterRequest true "Registration response"
+// @Success      201  {object}  PasskeyResponse
+// @Failure      400  {string}  string "Invalid passkey registration"
+// @Failure      500  {string}  string "Failed to store passkey"
+// @Router       /api/passkeys/register/finish [post]
+func FinishPasskeyRegistration(db *gorm.DB, tokenStore *authToken.TokenStore, wa *webauthn.WebAuthn) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var request PasskeyRegisterRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		session, err := consumePasskeyCeremony(tokenStore, request.SessionID)
+		if err != nil {
+			http.Error(w, "Invalid or expired passkey session", http.StatusBadRequest)
+			return
+		}
+
+		user, err := loadPasskeyUser(db, principal.UserID)
+		if err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		credential, err := passkey.FinishRegistration(wa, user, *session, bytes.NewReader(request.Credential))
+		if err != nil {
+			http.Error(w, "Invalid passkey registration", http.StatusBadRequest)
+			return
+		}
+
+		stored := passkey.FromWebAuthn(user.User.ID, request.Name, credential)
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&stored).Error; err != nil {
+				return err
+			}
+			_, err := identity.Link(tx, user.User.ID, db_models.IdentityPasskey, "", identity.PasskeySubject(stored.CredentialID), stored.Name)
+			return err
+		})
+		if err != nil {
+			http.Error(w, "Failed to store passkey", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(passkeyResponse(stored))
+	}
+}
+
+// ListPasskeys godoc
+// @Summary      List passkeys
+// @Description  List the passkeys registered by the authenticated user
+// @Security     BearerAuth
+// @Tags         passkeys
+// @Produce      json
+// @Success      200  {array}   PasskeyResponse
+// @Failure      500  {string}  string "Failed to list passkeys"
+// @Router       /api/passkeys [get]
+func ListPasskeys(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var credentials []db_models.WebAuthnCredential
+		if err := db.Where("user_id = ?", principal.UserID).Find(&credentials).Error; err != nil {
+			http.Error(w, "Failed to list passkeys", http.StatusInternalServerError)
+			return
+		}
+
+		response := []PasskeyResponse{}
+		for _, credential := range credentials {
+			response = append(response, passkeyResponse(credential))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// DeletePasskey godoc
+// @Summary      Delete a passkey
+// @Description  Remove one of the authenticated user's passkeys. The last remaining sign-in method can't be removed.
+// @Security     BearerAuth
+// @Tags         passkeys
+// @Param        id   path      string  true  "Passkey ID"
+// @Success      204  "No Content"
+// @Failure      404  {string}  string "Passkey not found"
+// @Failure      409  {string}  string "Can't remove the last sign-in method"
+// @Router       /api/passkeys/{id} [delete]
+func DeletePasskey(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var credential db_models.WebAuthnCredential
+		if err := db.Where("id = ? AND user_id = ?", chi.URLParam(r, "id"), principal.UserID).First(&credential).Error; err != nil {
+			http.Error(w, "Passkey not found", http.StatusNotFound)
+			return
+		}
+
+		// Passkey kimliği üzerinden silinir, böylece son giriş yöntemi korunur
+		linked, err := identity.Find(db, db_models.IdentityPasskey, "", identity.PasskeySubject(credential.CredentialID))
+		if err == nil {
+			_, err = identity.Unlink(db, principal.UserID, linked.ID)
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				http.Error(w, "Passkey not found", http.StatusNotFound)
+			case err == identity.ErrLastIdentity:
+				http.Error(w, "Can't remove the last sign-in method", http.StatusConflict)
+			default:
+				http.Error(w, "Failed to delete passkey", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// BeginPasskeyLogin godoc
+// @Summary      Start passkey login
+// @Description  Create WebAuthn assertion options for a discoverable passkey login
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  PasskeyBeginResponse
+// @Failure      500  {string}  string "Failed to start passkey login"
+// @Router       /api/passkeys/login/begin [post]
+func BeginPasskeyLogin(tokenStore *authToken.TokenStore, wa *webauthn.WebAuthn) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		options, session, err := wa.BeginDiscoverableLogin()
+		if err != nil {
+			http.Error(w, "Failed to start passkey login", http.StatusInternalServerError)
+			return
+		}
+
+		sessionID, err := storePasskeyCeremony(tokenStore, session)
+		if err != nil {
+			http.Error(w, "Failed to start passkey login", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(PasskeyBeginResponse{SessionID: sessionID, Options: options})
+	}
+}
+
+// FinishPasskeyLogin godoc
+// @Summary      Finish passkey login
+// @Description  Verify the passkey assertion and return access and refresh tokens like /api/login
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body PasskeyLoginRequest true "Assertion response"
+// @Success      200  {object}  LoginResponse
+// @Failure      400  {string}  string "Invalid request payload"
+// @Failure      401  {string}  string "Invalid passkey"
+// @Router       /api/passkeys/login/finish [post]
+func FinishPasskeyLogin(db *gorm.DB, tokenStore *authToken.TokenStore, m *melody.Melody, wa *webauthn.WebAuthn) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request PasskeyLoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		session, err := consumePasskeyCeremony(tokenStore, request.SessionID)
+		if err != nil {
+			http.Error(w, "Invalid or expired passkey session", http.StatusUnauthorized)
+			return
+		}
+
+		// Sayaç artmadıysa (kopyalanmış anahtar) passkey reddedilir
+		user, credential, err := passkey.FinishLogin(wa, *session, bytes.NewReader(request.Credential), func(userID uint) (*passkey.User, error) {
+			return loadPasskeyUser(db, userID)
+		})
+		if err != nil {
+			http.Error(w, "Invalid passkey", http.StatusUnauthorized)
+			return
+		}
+
+		now := time.Now()
+		if err := db.Model(&db_models.WebAuthnCredential{}).
+			Where("user_id = ? AND credential_id = ?", user.User.ID, credential.ID).
+			Updates(map[string]interface{}{"SignCount": credential.Authenticator.SignCount, "LastUsedAt": &now}).Error; err != nil {
+			http.Error(w, "Failed to update passkey", http.StatusInternalServerError)
+			return
+		}
+
+		// Passkey kullanıcı doğrulaması zorunlu olduğundan ayrıca TOTP istenmez
+		completeLogin(w, r, tokenStore, m, user.User, request.Lat, request.Lng, request.DeviceName)
+	}
+}
+
+func loadPasskeyUser(db *gorm.DB, userID uint) (*passkey.User, error) {
+	var user db_models.User
+	if err := db.Preload("Friends").Preload("Locations").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var credentials []db_models.WebAuthnCredential
+	if err := db.Where("user_id = ?", userID).Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+
+	return &passkey.User{User: user, Credentials: credentials}, nil
+}
+
+func storePasskeyCeremony(tokenStore *authToken.TokenStore, session *webauthn.SessionData) (string, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+	return tokenStore.StorePasskeyCeremony(data)
+}
+
+func consumePasskeyCeremony(tokenStore *authToken.TokenStore, sessionID string) (*webauthn.SessionData, error) {
+	data, err := tokenStore.ConsumePasskeyCeremony(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func passkeyResponse(credential db_models.WebAuthnCredential) PasskeyResponse {
+	return PasskeyResponse{
+		ID:         credential.ID,
+		Name:       credential.Name,
+		CreatedAt:  credential.CreatedAt,
+		LastUsedAt: credential.LastUsedAt,
+	}
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"net/url"
+	"svm/auth/authz"
+	"svm/auth/hashing"
+	"svm/auth/identity"
+	authJWT "svm/auth/jwt"
+	"svm/auth/policy"
+	"svm/auth/security"
+	authToken "svm/auth/token"
+	"svm/mail"
+	"svm/models/db_models"
+)
+
+// ForgotPasswordRequest represents the structure for requesting a password reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest represents the structure for setting a new password with a reset token
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// ForgotPassword godoc
+// @Summary      Request a password reset
+// @Description  Email a single-use, time-limited password reset link. The response is the same whether or not the address is registered.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body ForgotPasswordRequest true "Email address"
+// @Success      202  {object}  MessageResponse
+// @Failure      400  {string}  string "Invalid request payload"
+// @Router       /api/password/forgot [post]
+func ForgotPassword(db *gorm.DB, tokenStore *authToken.TokenStore, mailer mail.Mailer, resetURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request ForgotPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		// Kayıtlı olmayan adresler için de aynı cevap döner, hesap varlığı sızdırılmaz. E-posta
+		// arka planda gönderilir, cevap süresinden de hesabın var olduğu anlaşılmasın.
+		var user db_models.User
+		if err := db.Where("LOWER(email) = LOWER(?)", request.Email).First(&user).Error; err == nil && request.Email != "" {
+			go func() {
+				if err := sendPasswordResetEmail(tokenStore, mailer, resetURL, user); err != nil {
+					log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+				}
+			}()
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(MessageResponse{Message: "If the address is registered, a password reset link has been sent"})
+	}
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password with a reset token and log out every session of the user
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body ResetPasswordRequest true "Reset token and new password"
+// @Success      200  {object}  MessageResponse
+// @Failure      400  {object}  policy.ValidationError "Password does not meet the policy, or the reset token is invalid or expired"
+// @Failure      500  {string}  string "Failed to reset password"
+// @Router       /api/password/reset [post]
+func ResetPassword(db *gorm.DB, tokenStore *authToken.TokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request ResetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if request.NewPassword == "" {
+			http.Error(w, "New password is required", http.StatusBadRequest)
+			return
+		}
+
+		tokenHash := authToken.HashOpaqueToken(request.Token)
+		userID, err := tokenStore.LookupPasswordResetToken(tokenHash)
+		if err != nil {
+			if err == authToken.ErrPasswordResetNotFound {
+				http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
+			} else {
+				http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		var user db_models.User
+		if err := db.First(&user, userID).Error; err != nil {
+			http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+
+		// Politika ihlalinde link harcanmaz, kullanıcı başka bir şifreyle tekrar deneyebilir
+		if err := policy.DefaultPolicy.Check(request.NewPassword, user.Name, user.Email); err != nil {
+			writePasswordPolicyError(w, err)
+			return
+		}
+
+		// Link aynı anda iki kez kullanılamasın diye token burada tüketilir
+		if consumedID, err := tokenStore.ConsumePasswordResetToken(tokenHash); err != nil || consumedID != user.ID {
+			http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+
+		if err := hashing.SetPassword(&user, request.NewPassword); err != nil {
+			http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+			return
+		}
+		// Şifresi olmayan hesaplarda (ör. sağlayıcı ile açılmış) sıfırlama şifreyi giriş yöntemi olarak ekler
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Update("PasswordHash", user.PasswordHash).Error; err != nil {
+				return err
+			}
+			_, err := identity.Link(tx, user.ID, db_models.IdentityPassword, "", identity.PasswordSubject(user.ID), "")
+			return err
+		})
+		if err != nil {
+			http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+			return
+		}
+
+		// Şifre sıfırlandıktan sonra eski oturumların hiçbiri geçerli kalmamalı
+		if err := revokeAllUserTokens(tokenStore, user.ID, ""); err != nil {
+			http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+
+		security.Record(securityEvent(r, security.EventPasswordReset, user.ID))
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(MessageResponse{Message: "Password has been reset"})
+	}
+}
+
+func sendPasswordResetEmail(tokenStore *authToken.TokenStore, mailer mail.Mailer, resetURL string, user db_models.User) error {
+	token, tokenHash, err := authToken.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	if err := tokenStore.StorePasswordResetToken(user.ID, tokenHash); err != nil {
+		return err
+	}
+
+	link := resetURL + "?token=" + url.QueryEscape(token)
+	return mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. If it was you, open the link below:\n\n%s\n\nThe link expires in %s and can be used once. If you didn't ask for this, you can ignore this email.\n",
+			user.Name, link, authToken.PasswordResetDuration),
+	})
+}
+
+// writePasswordPolicyError şifre politikası ihlallerini yapılandırılmış JSON olarak döner
+func writePasswordPolicyError(w http.ResponseWriter, err error) {
+	var validationErr *policy.ValidationError
+	if !errors.As(err, &validationErr) {
+		http.Error(w, "Failed to check password", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(validationErr)
+}
+
+// revokeAllUserTokens kullanıcının keepSessionID dışındaki tüm oturumlarını kapatır.
+// keepSessionID boşsa access token'lar da hemen geçersiz olur.
+func revokeAllUserTokens(tokenStore *authToken.TokenStore, userID uint, keepSessionID string) error {
+	if err := tokenStore.RevokeAllSessions(userID, keepSessionID); err != nil {
+		return err
+	}
+	if keepSessionID != "" {
+		return nil
+	}
+	return tokenStore.RevokeUserAccessTokens(userID, authJWT.AccessTokenDuration)
+}
+
+// ChangePasswordRequest represents the structure for changing the password of the authenticated user
+type ChangePasswordRequest struct {
+	CurrentPassword    string `json:"current_password"`
+	NewPassword        string `json:"new_password"`
+	KeepCurrentSession bool   `json:"keep_current_session"`
+}
+
+// ChangePassword godoc
+// @Summary      Change password
+// @Description  Change the password of the authenticated user and log out every other session. The current session is kept only if keep_current_session is set.
+// @Security     BearerAuth
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body ChangePasswordRequest true "Current and new password"
+// @Success      200  {object}  MessageResponse
+// @Failure      400  {object}  policy.ValidationError "Password does not meet the policy"
+// @Failure      401  {string}  string "Current password is incorrect"
+// @Failure      500  {string}  string "Failed to change password"
+// @Router       /api/password/change [post]
+func ChangePassword(db *gorm.DB, tokenStore *authToken.TokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var request ChangePasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		var user db_models.User
+		if err := db.First(&user, principal.UserID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if !hashing.CheckPassword(&user, request.CurrentPassword) {
+			http.Error(w, "Current password is incorrect", http.StatusUnauthorized)
+			return
+		}
+
+		if err := policy.DefaultPolicy.Check(request.NewPassword, user.Name, user.Email); err != nil {
+			writePasswordPolicyError(w, err)
+			return
+		}
+		if request.NewPassword == request.CurrentPassword {
+			http.Error(w, policy.ErrPasswordUnchanged.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := hashing.SetPassword(&user, request.NewPassword); err != nil {
+			http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+			return
+		}
+		if err := db.Model(&user).Update("PasswordHash", user.PasswordHash).Error; err != nil {
+			http.Error(w, "Failed to change password", http.StatusInternalServerError)
+			return
+		}
+
+		// Diğer cihazlardaki oturumlar kapatılır, istenirse mevcut oturum korunur
+		keepSessionID := ""
+		if request.KeepCurrentSession {
+			keepSessionID = principal.SessionID
+		}
+		if err := revokeAllUserTokens(tokenStore, user.ID, keepSessionID); err != nil {
+			http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+
+		security.Record(securityEvent(r, security.EventPasswordChanged, user.ID))
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(MessageResponse{Message: "Password has been changed"})
+	}
+}
@@ -0,0 +1,575 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/olahol/melody"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"strings"
+	"svm/auth/authz"
+	"svm/auth/identity"
+	authJWT "svm/auth/jwt"
+	"svm/auth/phone"
+	"svm/auth/security"
+	authToken "svm/auth/token"
+	"svm/models/db_models"
+	"svm/sms"
+	"time"
+)
+
+// PhoneCodeRequest represents the structure for asking for a code sent by SMS
+type PhoneCodeRequest struct {
+	Phone string `json:"phone"` // E.164 veya ülke kodu olmadan yerel biçim
+}
+
+// PhoneRegisterRequest represents the structure for signing up with a phone number
+type PhoneRegisterRequest struct {
+	Phone      string  `json:"phone"`
+	Code       string  `json:"code"`
+	Name       string  `json:"name"`
+	Lat        float64 `json:"lat"`
+	Lng        float64 `json:"lng"`
+	DeviceName string  `json:"device_name"`
+}
+
+// PhoneLoginRequest represents the structure for logging in with a code sent by SMS
+type PhoneLoginRequest struct {
+	Phone      string  `json:"phone"`
+	Code       string  `json:"code"`
+	Lat        float64 `json:"lat"`
+	Lng        float64 `json:"lng"`
+	DeviceName string  `json:"device_name"`
+}
+
+// PhoneVerifyRequest represents the structure for confirming a phone number of the authenticated user
+type PhoneVerifyRequest struct {
+	Phone string `json:"phone"`
+	Code  string `json:"code"`
+}
+
+// SMSCodeRequest represents the structure for confirming an action with a code sent by SMS
+type SMSCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// MFASMSRequest represents the structure for asking for an SMS code during a two-step login
+type MFASMSRequest struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+// BeginPhoneRegistration godoc
+// @Summary      Start phone sign-up
+// @Description  Send a one-time code by SMS to a phone number that isn't registered yet. The response is the same whether or not the number is registered.
+// @Tags         phone
+// @Accept       json
+// @Produce      json
+// @Param        request body PhoneCodeRequest true "Phone number"
+// @Success      202  {object}  MessageResponse
+// @Failure      400  {string}  string "Invalid phone number"
+// @Failure      429  {string}  string "Too many attempts"
+// @Router       /api/phone/register/begin [post]
+func BeginPhoneRegistration(db *gorm.DB, tokenStore *authToken.TokenStore, sender sms.SMSSender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		number, ok := decodePhone(w, r)
+		if !ok {
+			return
+		}
+
+		// Kayıtlı numaraya kod gönderilmez, cevap aynı kalır
+		var count int64
+		if err := db.Model(&db_models.User{}).Where("phone = ?", number).Count(&count).Error; err != nil {
+			http.Error(w, "Failed to send code", http.StatusInternalServerError)
+			return
+		}
+		if count == 0 && !sendSMSCode(w, tokenStore, sender, authToken.SMSOTPRegister, number, "Your sign-up code is %s") {
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(MessageResponse{Message: "If the number can be registered, a code has been sent"})
+	}
+}
+
+// FinishPhoneRegistration godoc
+// @Summary      Finish phone sign-up
+// @Description  Create an account for a phone number with the code sent by SMS and log in. The account has no email or password; they can be added later.
+// @Tags         phone
+// @Accept       json
+// @Produce      json
+// @Param        request body PhoneRegisterRequest true "Phone number, code and name"
+// @Success      200  {object}  LoginResponse
+// @Failure      400  {string}  string "Invalid phone number"
+// @Failure      401  {string}  string "Invalid or expired code"
+// @Failure      409  {string}  string "Phone number is already registered"
+// @Router       /api/phone/register/finish [post]
+func FinishPhoneRegistration(db *gorm.DB, tokenStore *authToken.TokenStore, m *melody.Melody) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request PhoneRegisterRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		number, err := phone.Normalize(request.Phone)
+		if err != nil {
+			http.Error(w, "Invalid phone number", http.StatusBadRequest)
+			return
+		}
+		request.Name = strings.TrimSpace(request.Name)
+		if request.Name == "" {
+			http.Error(w, "Name is required", http.StatusBadRequest)
+			return
+		}
+
+		if !verifySMSCode(w, tokenStore, authToken.SMSOTPRegister, number, request.Code) {
+			return
+		}
+
+		now := time.Now()
+		user := db_models.User{
+			Name:            request.Name,
+			Phone:           number,
+			PhoneVerifiedAt: &now,
+			Role:            db_models.RoleUser,
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			_, err := identity.Link(tx, user.ID, db_models.IdentityPhone, "", number, number)
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) || errors.Is(err, identity.ErrAlreadyLinked) {
+				http.Error(w, "Phone number is already registered", http.StatusConflict)
+			} else {
+				http.Error(w, "Failed to create user", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		completeLogin(w, r, tokenStore, m, user, request.Lat, request.Lng, request.DeviceName)
+	}
+}
+
+// BeginPhoneLogin godoc
+// @Summary      Start phone login
+// @Description  Send a one-time login code by SMS to a registered phone number. The response is the same whether or not the number is registered.
+// @Tags         phone
+// @Accept       json
+// @Produce      json
+// @Param        request body PhoneCodeRequest true "Phone number"
+// @Success      202  {object}  MessageResponse
+// @Failure      400  {string}  string "Invalid phone number"
+// @Failure      429  {string}  string "Too many attempts"
+// @Router       /api/phone/login/begin [post]
+func BeginPhoneLogin(db *gorm.DB, tokenStore *authToken.TokenStore, sender sms.SMSSender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		number, ok := decodePhone(w, r)
+		if !ok {
+			return
+		}
+
+		var user db_models.User
+		if err := db.Where("phone = ?", number).First(&user).Error; err == nil && user.SuspendedAt == nil {
+			if !sendSMSCode(w, tokenStore, sender, authToken.SMSOTPLogin, number, "Your login code is %s") {
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(MessageResponse{Message: "If the number is registered, a code has been sent"})
+	}
+}
+
+// FinishPhoneLogin godoc
+// @Summary      Finish phone login
+// @Description  Log in with the code sent by SMS. Users with TOTP enabled get an MFA challenge instead, to be completed at /api/login/mfa. Users whose only second factor is SMS must log in with their password.
+// @Tags         phone
+// @Accept       json
+// @Produce      json
+// @Param        request body PhoneLoginRequest true "Phone number and code"
+// @Success      200  {object}  LoginResponse
+// @Success      202  {object}  MFAChallengeResponse
+// @Failure      400  {string}  string "Invalid phone number"
+// @Failure      401  {string}  string "Invalid or expired code"
+// @Failure      403  {string}  string "Account is suspended or has SMS two-factor authentication, which needs the password to log in"
+// @Router       /api/phone/login/finish [post]
+func FinishPhoneLogin(db *gorm.DB, tokenStore *authToken.TokenStore, m *melody.Melody) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request PhoneLoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		number, err := phone.Normalize(request.Phone)
+		if err != nil {
+			http.Error(w, "Invalid phone number", http.StatusBadRequest)
+			return
+		}
+
+		if !verifySMSCode(w, tokenStore, authToken.SMSOTPLogin, number, request.Code) {
+			return
+		}
+
+		var user db_models.User
+		if err := db.Preload("Friends").Preload("Locations").Where("phone = ?", number).First(&user).Error; err != nil {
+			http.Error(w, "Invalid or expired code", http.StatusUnauthorized)
+			return
+		}
+
+		switch phoneLoginNextStep(user) {
+		case phoneLoginChallenge:
+			writeMFAChallenge(w, user.ID, authJWT.FactorSMS)
+			return
+		case phoneLoginRefused:
+			http.Error(w, "Phone login is not available with SMS two-factor authentication, log in with your password", http.StatusForbidden)
+			return
+		}
+
+		completeLogin(w, r, tokenStore, m, user, request.Lat, request.Lng, request.DeviceName)
+	}
+}
+
+// SendPhoneVerification godoc
+// @Summary      Add a phone number
+// @Description  Send a one-time code by SMS to a number the authenticated user wants to add or switch to. The number is saved once confirmed at /api/phone/verify.
+// @Security     BearerAuth
+// @Tags         phone
+// @Accept       json
+// @Produce      json
+// @Param        request body PhoneCodeRequest true "Phone number"
+// @Success      202  {object}  MessageResponse
+// @Failure      400  {string}  string "Invalid phone number"
+// @Failure      409  {string}  string "Phone number is already registered"
+// @Failure      429  {string}  string "Too many attempts"
+// @Router       /api/phone [post]
+func SendPhoneVerification(db *gorm.DB, tokenStore *authToken.TokenStore, sender sms.SMSSender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		number, ok := decodePhone(w, r)
+		if !ok {
+			return
+		}
+
+		var count int64
+		if err := db.Model(&db_models.User{}).Where("phone = ? AND id <> ?", number, principal.UserID).Count(&count).Error; err != nil {
+			http.Error(w, "Failed to send code", http.StatusInternalServerError)
+			return
+		}
+		if count > 0 {
+			http.Error(w, "Phone number is already registered", http.StatusConflict)
+			return
+		}
+
+		if !sendSMSCode(w, tokenStore, sender, authToken.SMSOTPVerify, number, "Your verification code is %s") {
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(MessageResponse{Message: "A verification code has been sent"})
+	}
+}
+
+// VerifyPhone godoc
+// @Summary      Confirm a phone number
+// @Description  Save the phone number of the authenticated user with the code sent by SMS. A previous number is replaced and can no longer be used to sign in.
+// @Security     BearerAuth
+// @Tags         phone
+// @Accept       json
+// @Produce      json
+// @Param        request body PhoneVerifyRequest true "Phone number and code"
+// @Success      200  {object}  MessageResponse
+// @Failure      400  {string}  string "Invalid phone number"
+// @Failure      401  {string}  string "Invalid or expired code"
+// @Failure      409  {string}  string "Phone number is already registered"
+// @Router       /api/phone/verify [post]
+func VerifyPhone(db *gorm.DB, tokenStore *authToken.TokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var request PhoneVerifyRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		number, err := phone.Normalize(request.Phone)
+		if err != nil {
+			http.Error(w, "Invalid phone number", http.StatusBadRequest)
+			return
+		}
+
+		if !verifySMSCode(w, tokenStore, authToken.SMSOTPVerify, number, request.Code) {
+			return
+		}
+
+		now := time.Now()
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&db_models.User{}).Where("id = ?", principal.UserID).Updates(map[string]interface{}{
+				"Phone":           number,
+				"PhoneVerifiedAt": &now,
+			}).Error; err != nil {
+				return err
+			}
+			// Eski numara artık giriş yöntemi değildir
+			if err := tx.Unscoped().Where("user_id = ? AND type = ? AND subject <> ?", principal.UserID, db_models.IdentityPhone, number).
+				Delete(&db_models.Identity{}).Error; err != nil {
+				return err
+			}
+			_, err := identity.Link(tx, principal.UserID, db_models.IdentityPhone, "", number, number)
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) || errors.Is(err, identity.ErrAlreadyLinked) {
+				http.Error(w, "Phone number is already registered", http.StatusConflict)
+			} else {
+				http.Error(w, "Failed to save phone number", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(MessageResponse{Message: "Phone number has been verified"})
+	}
+}
+
+// SendMFASMSCode godoc
+// @Summary      Send a login code by SMS
+// @Description  During a two-step login, send a one-time code to the verified phone of a user with SMS two-factor authentication. The code is then sent to /api/login/mfa. Logins that started with an SMS code at /api/phone/login/finish need a TOTP or recovery code instead.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body MFASMSRequest true "MFA challenge"
+// @Success      202  {object}  MessageResponse
+// @Failure      401  {string}  string "Invalid or expired MFA token"
+// @Failure      409  {string}  string "Login started with an SMS code"
+// @Failure      429  {string}  string "Too many attempts"
+// @Router       /api/login/mfa/sms [post]
+func SendMFASMSCode(db *gorm.DB, tokenStore *authToken.TokenStore, sender sms.SMSSender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request MFASMSRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		claims, err := authJWT.ValidateToken(request.MFAToken, authJWT.TokenTypeMFA)
+		if err != nil {
+			http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+			return
+		}
+
+		// Telefon ilk adım olarak kullanıldıysa ikinci adım için tekrar SMS gönderilmez
+		if claims.FirstFactor == authJWT.FactorSMS {
+			http.Error(w, "Login started with an SMS code; use an authenticator or recovery code", http.StatusConflict)
+			return
+		}
+
+		var user db_models.User
+		if err := db.First(&user, claims.UserID).Error; err != nil || !user.SMSMFAEnabled || user.Phone == "" {
+			http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+			return
+		}
+
+		if !sendSMSCode(w, tokenStore, sender, authToken.SMSOTPMFA, user.Phone, "Your login code is %s") {
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(MessageResponse{Message: "A code has been sent"})
+	}
+}
+
+// SendSMSCode godoc
+// @Summary      Send a confirmation code by SMS
+// @Description  Send a one-time code to the verified phone of the authenticated user, to confirm turning SMS two-factor authentication on or off
+// @Security     BearerAuth
+// @Tags         mfa
+// @Produce      json
+// @Success      202  {object}  MessageResponse
+// @Failure      409  {string}  string "No verified phone number"
+// @Failure      429  {string}  string "Too many attempts"
+// @Router       /api/mfa/sms/code [post]
+func SendSMSCode(db *gorm.DB, tokenStore *authToken.TokenStore, sender sms.SMSSender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := loadPhoneUser(w, r, db)
+		if !ok {
+			return
+		}
+
+		if !sendSMSCode(w, tokenStore, sender, authToken.SMSOTPMFA, user.Phone, "Your confirmation code is %s") {
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(MessageResponse{Message: "A code has been sent"})
+	}
+}
+
+// EnableSMSMFA godoc
+// @Summary      Turn on SMS two-factor authentication
+// @Description  Require a code sent to the verified phone after the password, confirmed with a code from /api/mfa/sms/code
+// @Security     BearerAuth
+// @Tags         mfa
+// @Accept       json
+// @Param        request body SMSCodeRequest true "Code sent by SMS"
+// @Success      204  "No Content"
+// @Failure      401  {string}  string "Invalid or expired code"
+// @Failure      409  {string}  string "No verified phone number"
+// @Router       /api/mfa/sms/enable [post]
+func EnableSMSMFA(db *gorm.DB, tokenStore *authToken.TokenStore) http.HandlerFunc {
+	return setSMSMFA(db, tokenStore, true)
+}
+
+// DisableSMSMFA godoc
+// @Summary      Turn off SMS two-factor authentication
+// @Description  Stop asking for an SMS code at login, confirmed with a code from /api/mfa/sms/code
+// @Security     BearerAuth
+// @Tags         mfa
+// @Accept       json
+// @Param        request body SMSCodeRequest true "Code sent by SMS"
+// @Success      204  "No Content"
+// @Failure      401  {string}  string "Invalid or expired code"
+// @Failure      409  {string}  string "No verified phone number"
+// @Router       /api/mfa/sms/disable [post]
+func DisableSMSMFA(db *gorm.DB, tokenStore *authToken.TokenStore) http.HandlerFunc {
+	return setSMSMFA(db, tokenStore, false)
+}
+
+func setSMSMFA(db *gorm.DB, tokenStore *authToken.TokenStore, enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request SMSCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		user, ok := loadPhoneUser(w, r, db)
+		if !ok {
+			return
+		}
+
+		if !verifySMSCode(w, tokenStore, authToken.SMSOTPMFA, user.Phone, request.Code) {
+			return
+		}
+
+		if err := db.Model(&user).Update("SMSMFAEnabled", enabled).Error; err != nil {
+			http.Error(w, "Failed to update two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+
+		event := securityEvent(r, security.EventMFADisabled, user.ID)
+		if enabled {
+			event.Type = security.EventMFAEnabled
+		}
+		event.Details = "sms"
+		security.Record(event)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// requiresMFA kullanıcının girişte ikinci adım gerektirip gerektirmediğini döner
+func requiresMFA(user db_models.User) bool {
+	return user.TOTPEnabled || user.SMSMFAEnabled
+}
+
+type phoneLoginStep int
+
+const (
+	phoneLoginComplete phoneLoginStep = iota
+	phoneLoginChallenge
+	phoneLoginRefused
+)
+
+// phoneLoginNextStep SMS kodu doğrulandıktan sonra ne yapılacağını belirler.
+// SMS kodu zaten telefona sahip olmayı kanıtlar, ikinci adım olarak aynı telefona
+// ikinci bir SMS sayılmaz. TOTP açıksa o istenir, ikinci adımı yalnızca SMS olan
+// hesaplar ise parolayla giriş yapmalı.
+func phoneLoginNextStep(user db_models.User) phoneLoginStep {
+	switch {
+	case !requiresMFA(user):
+		return phoneLoginComplete
+	case user.TOTPEnabled:
+		return phoneLoginChallenge
+	default:
+		return phoneLoginRefused
+	}
+}
+
+// decodePhone PhoneCodeRequest'i okur ve numarayı E.164'e çevirir, hata olursa 400 döner
+func decodePhone(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var request PhoneCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return "", false
+	}
+
+	number, err := phone.Normalize(request.Phone)
+	if err != nil {
+		http.Error(w, "Invalid phone number", http.StatusBadRequest)
+		return "", false
+	}
+	return number, true
+}
+
+// loadPhoneUser doğrulanmış telefonu olan oturum sahibini yükler
+func loadPhoneUser(w http.ResponseWriter, r *http.Request, db *gorm.DB) (db_models.User, bool) {
+	principal := authz.MustPrincipal(r)
+
+	var user db_models.User
+	if err := db.First(&user, principal.UserID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return user, false
+	}
+	if user.Phone == "" || user.PhoneVerifiedAt == nil {
+		http.Error(w, "No verified phone number", http.StatusConflict)
+		return user, false
+	}
+	return user, true
+}
+
+// sendSMSCode yeni bir kod üretip saklar ve gönderir. message tek bir %s ile kodu içerir.
+func sendSMSCode(w http.ResponseWriter, tokenStore *authToken.TokenStore, sender sms.SMSSender, purpose authToken.SMSOTPPurpose, number, message string) bool {
+	code, err := phone.GenerateCode()
+	if err != nil {
+		http.Error(w, "Failed to send code", http.StatusInternalServerError)
+		return false
+	}
+
+	if err := tokenStore.StoreSMSOTP(purpose, number, authToken.HashOpaqueToken(code)); err != nil {
+		if err == authToken.ErrSMSOTPTooSoon {
+			writeTooManyAttempts(w, authToken.SMSOTPResendInterval)
+		} else {
+			http.Error(w, "Failed to send code", http.StatusInternalServerError)
+		}
+		return false
+	}
+
+	if err := sender.Send(number, fmt.Sprintf(message, code)); err != nil {
+		log.Printf("Failed to send SMS to %s: %v", number, err)
+		http.Error(w, "Failed to send code", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// verifySMSCode kodu kontrol eder, geçersizse 401 döner
+func verifySMSCode(w http.ResponseWriter, tokenStore *authToken.TokenStore, purpose authToken.SMSOTPPurpose, number, code string) bool {
+	err := tokenStore.VerifySMSOTP(purpose, number, authToken.HashOpaqueToken(strings.TrimSpace(code)))
+	if err == authToken.ErrSMSOTPInvalid {
+		http.Error(w, "Invalid or expired code", http.StatusUnauthorized)
+		return false
+	}
+	if err != nil {
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"testing"
+
+	"svm/models/db_models"
+)
+
+func TestPhoneLoginNextStep(t *testing.T) {
+	tests := []struct {
+		name string
+		user db_models.User
+		want phoneLoginStep
+	}{
+		{name: "no second factor", user: db_models.User{Phone: "+905551112233"}, want: phoneLoginComplete},
+		{name: "TOTP", user: db_models.User{PasswordHash: "hash", TOTPEnabled: true}, want: phoneLoginChallenge},
+		{name: "TOTP and SMS", user: db_models.User{PasswordHash: "hash", TOTPEnabled: true, SMSMFAEnabled: true}, want: phoneLoginChallenge},
+		// SMS kodu tek başına hem birinci hem ikinci adım sayılmamalı
+		{name: "password and SMS", user: db_models.User{PasswordHash: "hash", SMSMFAEnabled: true}, want: phoneLoginRefused},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := phoneLoginNextStep(test.user); got != test.want {
+				t.Fatalf("phoneLoginNextStep = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
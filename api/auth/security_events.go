@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"net/http"
+	"svm/auth/authz"
+	"svm/auth/security"
+	"svm/mail"
+	"svm/models/db_models"
+	"time"
+)
+
+// SecurityEventResponse represents one security relevant action on the user's account
+type SecurityEventResponse struct {
+	ID         uint      `json:"id"`
+	Type       string    `json:"type"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	DeviceName string    `json:"device_name,omitempty"`
+	Lat        float64   `json:"lat,omitempty"`
+	Lng        float64   `json:"lng,omitempty"`
+	Details    string    `json:"details,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// SecurityAlertMessage is sent over the WebSocket when a suspicious login is detected
+type SecurityAlertMessage struct {
+	Type      string    `json:"type"` // her zaman "security_alert"
+	Alert     string    `json:"alert"`
+	IP        string    `json:"ip"`
+	Device    string    `json:"device"`
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListSecurityEvents godoc
+// @Summary      List security events
+// @Description  List logins, failed attempts, token refreshes, logouts, password and MFA changes and suspicious login alerts of the authenticated user, newest first
+// @Security     BearerAuth
+// @Tags         security
+// @Produce      json
+// @Param        type     query     string  false  "Event type, e.g. login or new_device"
+// @Param        page     query     int     false  "Page number"
+// @Param        pageSize query     int     false  "Number of entries per page"
+// @Success      200  {array}   SecurityEventResponse
+// @Failure      500  {string}  string "Failed to fetch security events"
+// @Router       /api/security/events [get]
+func ListSecurityEvents(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+		page, pageSize := pagination(r)
+
+		query := db.Model(&db_models.SecurityEvent{}).Where("user_id = ?", principal.UserID).Order("id DESC")
+		if eventType := r.URL.Query().Get("type"); eventType != "" {
+			query = query.Where("type = ?", eventType)
+		}
+
+		var events []db_models.SecurityEvent
+		if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Find(&events).Error; err != nil {
+			http.Error(w, "Failed to fetch security events", http.StatusInternalServerError)
+			return
+		}
+
+		response := []SecurityEventResponse{}
+		for _, event := range events {
+			response = append(response, SecurityEventResponse{
+				ID:         event.ID,
+				Type:       event.Type,
+				IP:         event.IP,
+				UserAgent:  event.UserAgent,
+				DeviceName: event.DeviceName,
+				Lat:        event.Lat,
+				Lng:        event.Lng,
+				Details:    event.Details,
+				CreatedAt:  event.CreatedAt,
+			})
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// SecurityAlertNotifier tells the user about a suspicious login on the open
+// WebSocket connection and by email
+type SecurityAlertNotifier struct {
+	DB     *gorm.DB
+	Mailer mail.Mailer
+}
+
+func (notifier *SecurityAlertNotifier) Notify(ctx context.Context, alert security.Event) error {
+	device := alert.DeviceName
+	if device == "" {
+		device = alert.UserAgent
+	}
+
+	if session, ok := UserSessions.Get(fmt.Sprintf("%d", alert.UserID)); ok {
+		message, err := json.Marshal(SecurityAlertMessage{
+			Type:      "security_alert",
+			Alert:     string(alert.Type),
+			IP:        alert.IP,
+			Device:    device,
+			Details:   alert.Details,
+			CreatedAt: alert.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if err := session.Write(message); err != nil {
+			return err
+		}
+	}
+
+	// Telefonla kayıt olan kullanıcıların e-postası olmayabilir
+	var user db_models.User
+	if err := notifier.DB.WithContext(ctx).First(&user, alert.UserID).Error; err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return nil
+	}
+
+	subject := "New sign-in to your account"
+	if alert.Type == security.EventImpossibleTravel {
+		subject = "Suspicious sign-in to your account"
+	}
+	body := fmt.Sprintf("Hello %s,\n\nYour account was signed in to on %s.\n\nDevice: %s\nIP address: %s\n%s\n\n"+
+		"If this was you, you can ignore this email. Otherwise change your password and sign out your other sessions right away.\n",
+		user.Name, alert.CreatedAt.Format(time.RFC1123), device, alert.IP, alert.Details)
+
+	return notifier.Mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body:    body,
+	})
+}
+
+// securityEvent isteğin IP ve user agent bilgisiyle bir güvenlik olayı oluşturur
+func securityEvent(r *http.Request, eventType security.EventType, userID uint) security.Event {
+	return security.Event{
+		Type:      eventType,
+		UserID:    userID,
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+}
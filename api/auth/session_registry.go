@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"sync"
+
+	"github.com/olahol/melody"
+)
+
+// SessionRegistry maps user IDs to their open WebSocket connection. Melody runs the
+// connect and disconnect handlers on connection goroutines while request handlers
+// read the same map, so every access goes through the lock.
+type SessionRegistry struct {
+	mu       sync.RWMutex
+	sessions map[string]*melody.Session
+}
+
+// NewSessionRegistry creates an empty SessionRegistry
+func NewSessionRegistry() *SessionRegistry {
+	return &SessionRegistry{sessions: make(map[string]*melody.Session)}
+}
+
+// Get returns the connection of the user, if one is open
+func (registry *SessionRegistry) Get(userID string) (*melody.Session, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	session, ok := registry.sessions[userID]
+	return session, ok
+}
+
+// Set stores the connection of the user, replacing an earlier one
+func (registry *SessionRegistry) Set(userID string, session *melody.Session) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.sessions[userID] = session
+}
+
+// Remove forgets the connection of the user and returns it so the caller can close it
+func (registry *SessionRegistry) Remove(userID string) (*melody.Session, bool) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	session, ok := registry.sessions[userID]
+	delete(registry.sessions, userID)
+	return session, ok
+}
+
+// RemoveSession forgets a closed connection. A newer connection of the same user is kept.
+func (registry *SessionRegistry) RemoveSession(session *melody.Session) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	for userID, s := range registry.sessions {
+		if s == session {
+			delete(registry.sessions, userID)
+			return
+		}
+	}
+}
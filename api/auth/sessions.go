@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"io"
+	"net/http"
+	"svm/auth/authz"
+	authToken "svm/auth/token"
+	"time"
+)
+
+// SessionResponse represents one logged-in device of the user
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Current    bool      `json:"current"`
+}
+
+// RevokeAllSessionsRequest represents the structure for the revoke-all request
+type RevokeAllSessionsRequest struct {
+	KeepCurrent bool `json:"keep_current"`
+}
+
+// ListSessions godoc
+// @Summary      List sessions
+// @Description  List the devices the authenticated user is logged in on
+// @Security     BearerAuth
+// @Tags         sessions
+// @Produce      json
+// @Success      200  {array}   SessionResponse
+// @Failure      500  {string}  string "Failed to list sessions"
+// @Router       /api/sessions [get]
+func ListSessions(tokenStore *authToken.TokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		sessions, err := tokenStore.ListSessions(principal.UserID)
+		if err != nil {
+			http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+			return
+		}
+
+		response := []SessionResponse{}
+		for _, session := range sessions {
+			response = append(response, SessionResponse{
+				ID:         session.ID,
+				DeviceName: session.DeviceName,
+				UserAgent:  session.UserAgent,
+				IP:         session.IP,
+				CreatedAt:  session.CreatedAt,
+				LastUsedAt: session.LastUsedAt,
+				Current:    session.ID == principal.SessionID,
+			})
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// RevokeSession godoc
+// @Summary      Revoke a session
+// @Description  Log out one device of the authenticated user
+// @Security     BearerAuth
+// @Tags         sessions
+// @Param        id   path      string  true  "Session ID"
+// @Success      204  "No Content"
+// @Failure      404  {string}  string "Session not found"
+// @Failure      500  {string}  string "Failed to revoke session"
+// @Router       /api/sessions/{id} [delete]
+func RevokeSession(tokenStore *authToken.TokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		if err := tokenStore.RevokeSession(principal.UserID, chi.URLParam(r, "id")); err != nil {
+			if err == authToken.ErrSessionNotFound {
+				http.Error(w, "Session not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RevokeAllSessions godoc
+// @Summary      Revoke all sessions
+// @Description  Log out every device of the authenticated user, optionally keeping the current one
+// @Security     BearerAuth
+// @Tags         sessions
+// @Accept       json
+// @Param        request body RevokeAllSessionsRequest false "Revoke options"
+// @Success      204  "No Content"
+// @Failure      400  {string}  string "Invalid request payload"
+// @Failure      500  {string}  string "Failed to revoke sessions"
+// @Router       /api/sessions/revoke-all [post]
+func RevokeAllSessions(tokenStore *authToken.TokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var request RevokeAllSessionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		keepSessionID := ""
+		if request.KeepCurrent {
+			keepSessionID = principal.SessionID
+		}
+
+		if err := tokenStore.RevokeAllSessions(principal.UserID, keepSessionID); err != nil {
+			http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/olahol/melody"
+	"log"
+	"net/http"
+	"strings"
+	"svm/auth/authz"
+	authToken "svm/auth/token"
+	smvmmiddleware "svm/middleware"
+)
+
+const (
+	// WebSocketSubprotocol is sent by clients as "bearer, <access token>" in Sec-WebSocket-Protocol
+	WebSocketSubprotocol = "bearer"
+	// WebSocketUserKey is the melody session key holding the authenticated user id
+	WebSocketUserKey = "user_id"
+)
+
+// WebSocketTicketResponse represents the structure for the WebSocket ticket response
+type WebSocketTicketResponse struct {
+	Ticket    string `json:"ticket"`
+	ExpiresIn int    `json:"expires_in"` // saniye
+}
+
+// CreateWebSocketTicket godoc
+// @Summary      Create a WebSocket ticket
+// @Description  Issue a short-lived, single-use ticket for opening a WebSocket connection as /ws?ticket=<ticket>. Browsers can't set an Authorization header on WebSocket requests, so they use this instead of the access token.
+// @Security     BearerAuth
+// @Tags         websocket
+// @Produce      json
+// @Success      201  {object}  WebSocketTicketResponse
+// @Failure      500  {string}  string "Failed to create ticket"
+// @Router       /api/ws/ticket [post]
+func CreateWebSocketTicket(tokenStore *authToken.TokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		ticket, err := tokenStore.StoreWebSocketTicket(principal.UserID)
+		if err != nil {
+			http.Error(w, "Failed to create ticket", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(WebSocketTicketResponse{
+			Ticket:    ticket,
+			ExpiresIn: int(authToken.WebSocketTicketDuration.Seconds()),
+		})
+	}
+}
+
+// WebSocket godoc
+// @Summary      Open a WebSocket connection
+// @Description  Upgrade to a WebSocket for live location updates. Authenticate with a ticket from /api/ws/ticket, or send the access token as the subprotocols "bearer, <token>".
+// @Tags         websocket
+// @Param        ticket  query     string  false  "Single-use connection ticket"
+// @Success      101  "Switching Protocols"
+// @Failure      401  {string}  string "Missing or invalid WebSocket credentials"
+// @Router       /ws [get]
+func WebSocket(m *melody.Melody, tokenStore *authToken.TokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := authenticateWebSocket(r, tokenStore)
+		if err != nil {
+			http.Error(w, "Missing or invalid WebSocket credentials", http.StatusUnauthorized)
+			return
+		}
+
+		// Kullanıcı kimliği bağlantı boyunca session'da tutulur, URL'den tekrar okunmaz
+		keys := map[string]interface{}{WebSocketUserKey: fmt.Sprintf("%d", userID)}
+		if err := m.HandleRequestWithKeys(w, r, keys); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// WebSocketUserID returns the authenticated user id bound to a session by WebSocket
+func WebSocketUserID(s *melody.Session) (string, bool) {
+	value, ok := s.Get(WebSocketUserKey)
+	if !ok {
+		return "", false
+	}
+	userID, ok := value.(string)
+	return userID, ok
+}
+
+// authenticateWebSocket önce tek kullanımlık ticket'a, yoksa subprotocol içindeki access token'a bakar
+func authenticateWebSocket(r *http.Request, tokenStore *authToken.TokenStore) (uint, error) {
+	if ticket := r.URL.Query().Get("ticket"); ticket != "" {
+		return tokenStore.ConsumeWebSocketTicket(ticket)
+	}
+
+	token, ok := subprotocolToken(r)
+	if !ok {
+		return 0, errors.New("websocket: no credentials")
+	}
+	claims, err := smvmmiddleware.AuthenticateAccessToken(tokenStore, token)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
+
+// subprotocolToken reads the token from "Sec-WebSocket-Protocol: bearer, <token>"
+func subprotocolToken(r *http.Request) (string, bool) {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			protocols = append(protocols, strings.TrimSpace(protocol))
+		}
+	}
+
+	for i := 0; i+1 < len(protocols); i++ {
+		if protocols[i] == WebSocketSubprotocol {
+			return protocols[i+1], true
+		}
+	}
+	return "", false
+}
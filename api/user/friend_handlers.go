@@ -0,0 +1,235 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"strconv"
+	"svm/auth/authz"
+	smvmmiddleware "svm/middleware"
+	"svm/models/api_models"
+	"svm/models/db_models"
+)
+
+// AddFriend godoc
+// @Summary      Send a friend request
+// @Description  Ask another user to become friends with the authenticated user. Friends can see each other's profile and locations, so the friendship only starts once the other user accepts at /api/users/friends/requests/{id}/accept. If the other user has already asked, the friendship starts right away. Only admins may set user_id to someone else.
+// @Security     BearerAuth
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        friend body api_models.FriendRequest true "Friend request data"
+// @Success      201  {string}  string "Friend added successfully"
+// @Success      202  {string}  string "Friend request sent"
+// @Failure      400  {string}  string "Invalid request payload"
+// @Failure      403  {string}  string "Forbidden"
+// @Failure      404  {string}  string "User not found"
+// @Failure      409  {string}  string "Already friends"
+// @Failure      500  {string}  string "Failed to add friend"
+// @Router       /api/users/friends [post]
+func AddFriend(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var request api_models.FriendRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		// user_id verilmezse işlemi yapan kullanıcı kullanılır, başkası adına sadece yöneticiler ekleyebilir
+		if request.UserID == 0 {
+			request.UserID = principal.UserID
+		}
+		if !authz.CanAddFriend(principal, request.UserID) {
+			smvmmiddleware.Forbidden(w)
+			return
+		}
+		if request.FriendID == 0 || request.FriendID == request.UserID {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		var friend db_models.User
+		if err := db.First(&friend, request.FriendID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		var friends int64
+		if err := db.Model(&db_models.Friend{}).Where("user_id = ? AND friend_id = ?", request.UserID, request.FriendID).Count(&friends).Error; err != nil {
+			http.Error(w, "Failed to add friend", http.StatusInternalServerError)
+			return
+		}
+		if friends > 0 {
+			http.Error(w, "Already friends", http.StatusConflict)
+			return
+		}
+
+		// Karşı taraf zaten istek gönderdiyse bu istek onu kabul etmek demektir
+		var incoming db_models.FriendRequest
+		err := db.Where("user_id = ? AND friend_id = ?", request.FriendID, request.UserID).First(&incoming).Error
+		switch {
+		case err == nil:
+			if err := acceptFriendRequest(db, incoming); err != nil {
+				http.Error(w, "Failed to add friend", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode("Friend added successfully")
+			return
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			http.Error(w, "Failed to add friend", http.StatusInternalServerError)
+			return
+		}
+
+		// Aynı istek tekrar gönderilirse yeni kayıt açılmaz
+		pending := db_models.FriendRequest{UserID: request.UserID, FriendID: request.FriendID}
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&pending).Error; err != nil {
+			http.Error(w, "Failed to add friend", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode("Friend request sent")
+	}
+}
+
+// ListFriendRequests godoc
+// @Summary      List incoming friend requests
+// @Description  List the pending friend requests sent to the authenticated user, oldest first
+// @Security     BearerAuth
+// @Tags         users
+// @Produce      json
+// @Success      200  {array}   api_models.FriendRequestResponse
+// @Failure      500  {string}  string "Failed to fetch friend requests"
+// @Router       /api/users/friends/requests [get]
+func ListFriendRequests(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		var requests []db_models.FriendRequest
+		if err := db.Preload("User").Where("friend_id = ?", principal.UserID).Order("id").Find(&requests).Error; err != nil {
+			http.Error(w, "Failed to fetch friend requests", http.StatusInternalServerError)
+			return
+		}
+
+		response := []api_models.FriendRequestResponse{}
+		for _, request := range requests {
+			response = append(response, api_models.FriendRequestResponse{
+				ID:        request.ID,
+				UserID:    request.UserID,
+				Name:      request.User.Name,
+				CreatedAt: request.CreatedAt,
+			})
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// AcceptFriendRequest godoc
+// @Summary      Accept a friend request
+// @Description  Accept a friend request sent to the authenticated user and start the friendship
+// @Security     BearerAuth
+// @Tags         users
+// @Param        id   path      string  true  "Friend request ID"
+// @Success      201  {string}  string "Friend added successfully"
+// @Failure      404  {string}  string "Friend request not found"
+// @Failure      500  {string}  string "Failed to add friend"
+// @Router       /api/users/friends/requests/{id}/accept [post]
+func AcceptFriendRequest(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		request, ok := loadFriendRequest(w, r, db)
+		if !ok {
+			return
+		}
+		// Sadece isteği alan kullanıcı kabul edebilir
+		if request.FriendID != principal.UserID {
+			http.Error(w, "Friend request not found", http.StatusNotFound)
+			return
+		}
+
+		if err := acceptFriendRequest(db, request); err != nil {
+			http.Error(w, "Failed to add friend", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode("Friend added successfully")
+	}
+}
+
+// DeleteFriendRequest godoc
+// @Summary      Decline or cancel a friend request
+// @Description  Decline a friend request sent to the authenticated user, or cancel one the user sent
+// @Security     BearerAuth
+// @Tags         users
+// @Param        id   path      string  true  "Friend request ID"
+// @Success      204  "No Content"
+// @Failure      404  {string}  string "Friend request not found"
+// @Failure      500  {string}  string "Failed to delete friend request"
+// @Router       /api/users/friends/requests/{id} [delete]
+func DeleteFriendRequest(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
+		request, ok := loadFriendRequest(w, r, db)
+		if !ok {
+			return
+		}
+		if request.FriendID != principal.UserID && request.UserID != principal.UserID {
+			http.Error(w, "Friend request not found", http.StatusNotFound)
+			return
+		}
+
+		// Kalıcı silinir, aynı istek daha sonra tekrar gönderilebilsin
+		if err := db.Unscoped().Delete(&request).Error; err != nil {
+			http.Error(w, "Failed to delete friend request", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// loadFriendRequest URL'deki isteği bulur, yoksa 404 döner
+func loadFriendRequest(w http.ResponseWriter, r *http.Request, db *gorm.DB) (db_models.FriendRequest, bool) {
+	var request db_models.FriendRequest
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Friend request not found", http.StatusNotFound)
+		return request, false
+	}
+	if err := db.First(&request, uint(id)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Friend request not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to load friend request", http.StatusInternalServerError)
+		}
+		return request, false
+	}
+	return request, true
+}
+
+// acceptFriendRequest arkadaşlığı iki yönde de oluşturur ve isteği siler
+func acceptFriendRequest(db *gorm.DB, request db_models.FriendRequest) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		friends := []db_models.Friend{
+			{UserID: request.UserID, FriendID: request.FriendID},
+			{UserID: request.FriendID, FriendID: request.UserID},
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&friends).Error; err != nil {
+			return err
+		}
+		// Karşılıklı gönderilmiş istekler varsa ikisi de kapanır
+		return tx.Unscoped().Where("(user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)",
+			request.UserID, request.FriendID, request.FriendID, request.UserID).Delete(&db_models.FriendRequest{}).Error
+	})
+}
@@ -2,34 +2,56 @@ package user
 
 import (
 	"encoding/json"
-	"github.com/gorilla/mux"
+	"errors"
+	"github.com/go-chi/chi/v5"
 	"gorm.io/gorm"
+	"log"
 	"net/http"
 	"strconv"
+	"svm/auth/authz"
 	"svm/auth/hashing"
+	"svm/auth/identity"
+	authJWT "svm/auth/jwt"
+	"svm/auth/policy"
+	authToken "svm/auth/token"
+	"svm/auth/verification"
+	smvmmiddleware "svm/middleware"
 	"svm/models/api_models"
 	"svm/models/db_models"
 )
 
 // CreateUser godoc
 // @Summary      Create a new user
-// @Description  Create a new user with the given details
+// @Description  Create a new unverified user with the given details and email a verification link
 // @Security     BearerAuth
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        user body api_models.CreateUserRequest true "User data"
 // @Success      201  {object}  api_models.UserResponse
-// @Failure      400  {string}  string "Invalid request payload"
+// @Failure      400  {object}  policy.ValidationError "Password does not meet the policy"
 // @Failure      500  {string}  string "Failed to create user"
 // @Router       /api/users [post]
-func CreateUser(db *gorm.DB) http.HandlerFunc {
+func CreateUser(db *gorm.DB, sender *verification.Sender) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req api_models.CreateUserRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid request payload", http.StatusBadRequest)
 			return
 		}
+		req.Email = identity.NormalizeEmail(req.Email)
+
+		if err := policy.DefaultPolicy.Check(req.Password, req.Name, req.Email); err != nil {
+			var validationErr *policy.ValidationError
+			if !errors.As(err, &validationErr) {
+				http.Error(w, "Failed to check password", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(validationErr)
+			return
+		}
 
 		// Şifreyi hashleyelim
 		hashedPassword, err := hashing.HashPassword(req.Password)
@@ -47,12 +69,24 @@ func CreateUser(db *gorm.DB) http.HandlerFunc {
 			ShareAddress: req.ShareAddress,
 		}
 
-		// Kullanıcıyı veritabanına kaydet
-		if err := db.Create(&user).Error; err != nil {
+		// Kullanıcıyı veritabanına kaydet, şifre ilk giriş yöntemi olarak bağlanır
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			_, err := identity.Link(tx, user.ID, db_models.IdentityPassword, "", identity.PasswordSubject(user.ID), "")
+			return err
+		})
+		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		// Hesap e-posta doğrulanana kadar doğrulanmamış kalır, gönderim hatası kaydı engellemez
+		if err := sender.Send(user); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+
 		// Response için user verisini struct'a dönüştürme
 		userResponse := api_models.UserResponse{
 			ID:      user.ID,
@@ -79,11 +113,23 @@ func CreateUser(db *gorm.DB) http.HandlerFunc {
 // @Failure      400  {string}  string "Invalid request payload"
 // @Failure      404  {string}  string "User not found"
 // @Failure      500  {string}  string "Failed to update user"
+// @Failure      403  {string}  string "Forbidden"
 // @Router       /api/users/{id} [put]
 func UpdateUser(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		id := vars["id"]
+		principal := authz.MustPrincipal(r)
+
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		// Kullanıcı sadece kendi profilini güncelleyebilir, moderatörler herkesinkini
+		if !authz.CanUpdateUser(principal, uint(id)) {
+			smvmmiddleware.Forbidden(w)
+			return
+		}
 
 		var user db_models.User
 		if err := db.First(&user, id).Error; err != nil {
@@ -186,17 +232,52 @@ func ListUsers(db *gorm.DB) http.HandlerFunc {
 // @Success      204  "No Content"
 // @Failure      404  {string}  string "User not found"
 // @Failure      500  {string}  string "Failed to delete user"
+// @Failure      403  {string}  string "Forbidden"
 // @Router       /api/users/{id} [delete]
-func DeleteUser(db *gorm.DB) http.HandlerFunc {
+func DeleteUser(db *gorm.DB, tokenStore *authToken.TokenStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		id := vars["id"]
+		principal := authz.MustPrincipal(r)
 
-		if err := db.Delete(&db_models.User{}, id).Error; err != nil {
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
 			http.Error(w, "User not found", http.StatusNotFound)
 			return
 		}
 
+		// Hesabı sadece sahibi veya bir yönetici silebilir
+		if !authz.CanDeleteUser(principal, uint(id)) {
+			smvmmiddleware.Forbidden(w)
+			return
+		}
+
+		var user db_models.User
+		if err := db.First(&user, id).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		// Kimlikler kalıcı silinir, aynı sağlayıcı hesabı veya telefon başka bir hesaba bağlanabilsin
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&db_models.Identity{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&user).Error
+		})
+		if err != nil {
+			http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+			return
+		}
+
+		// Silinen kullanıcının tüm oturumlarını ve hâlâ geçerli olan access token'larını iptal et
+		if err := tokenStore.RevokeAllSessions(user.ID, ""); err != nil {
+			http.Error(w, "Failed to revoke user sessions", http.StatusInternalServerError)
+			return
+		}
+		if err := tokenStore.RevokeUserAccessTokens(user.ID, authJWT.AccessTokenDuration); err != nil {
+			http.Error(w, "Failed to revoke user tokens", http.StatusInternalServerError)
+			return
+		}
+
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -211,11 +292,18 @@ func DeleteUser(db *gorm.DB) http.HandlerFunc {
 // @Success      200  {object}  api_models.UserResponse
 // @Failure      404  {string}  string "User not found"
 // @Failure      500  {string}  string "Failed to fetch user"
+// @Failure      403  {string}  string "Forbidden"
 // @Router       /api/users/{id} [get]
 func GetUserByID(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		id := vars["id"]
+		principal := authz.MustPrincipal(r)
+
+		// Sayı olmayan ID ham SQL olarak sorguya eklenmesin
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
 
 		var user db_models.User
 		if err := db.Preload("Friends").Preload("Locations").First(&user, id).Error; err != nil {
@@ -227,6 +315,12 @@ func GetUserByID(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		// Konumlar hassas veri, sadece kullanıcının kendisi ve arkadaşları görebilir
+		if !authz.CanViewUser(principal, user) {
+			smvmmiddleware.Forbidden(w)
+			return
+		}
+
 		// Friends verisini UserResponse struct'ına dönüştürme
 		var friendResponses []api_models.FriendResponse
 		for _, friend := range user.Friends {
@@ -261,55 +355,9 @@ func GetUserByID(db *gorm.DB) http.HandlerFunc {
 	}
 }
 
-// AddFriend godoc
-// @Summary      Add a friend
-// @Description  Create a friendship between two users
-// @Security     BearerAuth
-// @Tags         users
-// @Accept       json
-// @Produce      json
-// @Param        friend body api_models.FriendRequest true "Friend request data"
-// @Success      201  {string}  string "Friend added successfully"
-// @Failure      400  {string}  string "Invalid request payload"
-// @Failure      500  {string}  string "Failed to add friend"
-// @Router       /api/users/friends [post]
-func AddFriend(db *gorm.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var request api_models.FriendRequest
-		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
-			return
-		}
-
-		friend := db_models.Friend{
-			UserID:   request.UserID,
-			FriendID: request.FriendID,
-		}
-
-		// Arkadaşlık ilişkisini her iki yönde de ekleyelim
-		if err := db.Create(&friend).Error; err != nil {
-			http.Error(w, "Failed to add friend", http.StatusInternalServerError)
-			return
-		}
-
-		reverseFriend := db_models.Friend{
-			UserID:   request.FriendID,
-			FriendID: request.UserID,
-		}
-
-		if err := db.Create(&reverseFriend).Error; err != nil {
-			http.Error(w, "Failed to add reverse friend", http.StatusInternalServerError)
-			return
-		}
-
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode("Friend added successfully")
-	}
-}
-
 // AddUserLocation godoc
 // @Summary      Add a user location
-// @Description  Add a location for a user
+// @Description  Add a location for the authenticated user
 // @Security     BearerAuth
 // @Tags         users
 // @Accept       json
@@ -318,15 +366,27 @@ func AddFriend(db *gorm.DB) http.HandlerFunc {
 // @Success      201  {object}  db_models.UserLocation
 // @Failure      400  {string}  string "Invalid request payload"
 // @Failure      500  {string}  string "Failed to add location"
+// @Failure      403  {string}  string "Forbidden"
 // @Router       /api/users/locations [post]
 func AddUserLocation(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		principal := authz.MustPrincipal(r)
+
 		var req api_models.UserLocationRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid request payload", http.StatusBadRequest)
 			return
 		}
 
+		// Konum her zaman token sahibine eklenir
+		if req.UserID == 0 {
+			req.UserID = principal.UserID
+		}
+		if !authz.CanAddLocation(principal, req.UserID) {
+			smvmmiddleware.Forbidden(w)
+			return
+		}
+
 		userLocation := db_models.UserLocation{
 			UserID:    req.UserID,
 			Latitude:  req.Latitude,
@@ -0,0 +1,64 @@
+package authz
+
+import (
+	"svm/models/db_models"
+)
+
+// roleRank orders roles so that a higher role includes every lower one
+var roleRank = map[db_models.Role]int{
+	db_models.RoleUser:      1,
+	db_models.RoleModerator: 2,
+	db_models.RoleAdmin:     3,
+}
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role db_models.Role) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// expandRole returns role together with every role below it
+func expandRole(role db_models.Role) []db_models.Role {
+	roles := []db_models.Role{}
+	for r, rank := range roleRank {
+		if rank <= roleRank[role] {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// CanViewUser allows users to see themselves and their friends; moderators see everyone.
+// Friendships only exist once both users agreed, see user.AcceptFriendRequest.
+// target.Friends must be loaded.
+func CanViewUser(p *Principal, target db_models.User) bool {
+	if p.IsSelf(target.ID) || p.HasRole(db_models.RoleModerator) {
+		return true
+	}
+	for _, friend := range target.Friends {
+		if friend.ID == p.UserID {
+			return true
+		}
+	}
+	return false
+}
+
+// CanUpdateUser allows users to edit their own profile; moderators can edit any profile
+func CanUpdateUser(p *Principal, userID uint) bool {
+	return p.IsSelf(userID) || p.HasRole(db_models.RoleModerator)
+}
+
+// CanDeleteUser allows users to delete their own account; admins can delete any account
+func CanDeleteUser(p *Principal, userID uint) bool {
+	return p.IsSelf(userID) || p.HasRole(db_models.RoleAdmin)
+}
+
+// CanAddFriend allows users to create friendships for themselves only; admins can link any two users
+func CanAddFriend(p *Principal, userID uint) bool {
+	return p.IsSelf(userID) || p.HasRole(db_models.RoleAdmin)
+}
+
+// CanAddLocation allows users to add locations to their own account only
+func CanAddLocation(p *Principal, userID uint) bool {
+	return p.IsSelf(userID)
+}
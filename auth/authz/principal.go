@@ -0,0 +1,119 @@
+package authz
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	authJWT "svm/auth/jwt"
+	"svm/models/db_models"
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	UserID    uint
+	Email     string
+	Name      string
+	Roles     []db_models.Role // token'daki rol ve altındaki tüm roller
+	Scopes    []string
+	SessionID string
+	TokenID   string
+	// ImpersonatorID is the administrator acting as this user, zero for normal logins
+	ImpersonatorID uint
+	// APIKeyID is set when the request was authenticated with an API key instead of a token
+	APIKeyID uint
+}
+
+// contextKey is unexported so only this package can store or read the principal
+type contextKey struct{}
+
+// PrincipalFromClaims builds the principal of a validated access token
+func PrincipalFromClaims(claims *authJWT.Claims) *Principal {
+	role := db_models.Role(claims.Role)
+	if !ValidRole(role) {
+		role = db_models.RoleUser
+	}
+
+	return &Principal{
+		UserID:         claims.UserID,
+		Email:          claims.Email,
+		Name:           claims.Name,
+		Roles:          expandRole(role),
+		Scopes:         claims.Scopes,
+		SessionID:      claims.SessionID,
+		TokenID:        claims.ID,
+		ImpersonatorID: claims.ImpersonatorID,
+	}
+}
+
+// PrincipalFromAPIKey builds the principal of a valid API key owned by user
+func PrincipalFromAPIKey(key db_models.APIKey, user db_models.User) *Principal {
+	role := user.Role
+	if !ValidRole(role) {
+		role = db_models.RoleUser
+	}
+
+	return &Principal{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Name:     user.Name,
+		Roles:    expandRole(role),
+		Scopes:   strings.Fields(key.Scopes),
+		APIKeyID: key.ID,
+	}
+}
+
+// WithPrincipal returns a copy of ctx carrying p
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored by WithPrincipal
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// PrincipalFromRequest returns the principal of an authenticated request
+func PrincipalFromRequest(r *http.Request) (*Principal, bool) {
+	return PrincipalFromContext(r.Context())
+}
+
+// MustPrincipal returns the principal of a request behind JWTAuthentication.
+// It panics if the route was registered without authentication.
+func MustPrincipal(r *http.Request) *Principal {
+	p, ok := PrincipalFromRequest(r)
+	if !ok {
+		panic("authz: request has no principal, route is missing JWTAuthentication")
+	}
+	return p
+}
+
+// HasScope reports whether the principal was granted the given scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports whether the principal has role, directly or through a higher role
+func (p *Principal) HasRole(role db_models.Role) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsImpersonated reports whether an administrator is acting as the user
+func (p *Principal) IsImpersonated() bool {
+	return p.ImpersonatorID != 0
+}
+
+// IsSelf reports whether the principal is the given user
+func (p *Principal) IsSelf(userID uint) bool {
+	return p.UserID == userID
+}
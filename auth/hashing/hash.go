@@ -1,29 +1,88 @@
 package hashing
 
 import (
-	"golang.org/x/crypto/bcrypt"
+	"sync"
+
 	"svm/models/db_models"
 )
 
+// DefaultHasher yeni şifreler için kullanılır (OWASP önerilen argon2id parametreleri)
+var DefaultHasher Hasher = Argon2idHasher{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Hashers lists every algorithm whose hashes can still be verified, DefaultHasher first
+var Hashers = []Hasher{
+	DefaultHasher,
+	BcryptHasher{Cost: 10},
+}
+
 func SetPassword(user *db_models.User, password string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := HashPassword(password)
 	if err != nil {
 		return err
 	}
-	user.PasswordHash = string(hash)
+	user.PasswordHash = hash
 	return nil
 }
 
+// CheckPassword verifies the password against the stored hash. When the hash was
+// made with an outdated algorithm or parameters, user.PasswordHash is replaced by a
+// fresh hash, and the caller should persist it; see NeedsUpgrade.
 func CheckPassword(user *db_models.User, password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
-	return err == nil
+	hasher := hasherFor(user.PasswordHash)
+	if hasher == nil {
+		// Şifresi olmayan hesaplar da aynı sürede reddedilir
+		SimulatePasswordCheck(password)
+		return false
+	}
+
+	ok, err := hasher.Verify(user.PasswordHash, password)
+	if err != nil || !ok {
+		return false
+	}
+
+	if NeedsUpgrade(user.PasswordHash) {
+		// Yeniden hashleme başarısız olursa eski hash ile devam edilir
+		if hash, err := DefaultHasher.Hash(password); err == nil {
+			user.PasswordHash = hash
+		}
+	}
+	return true
 }
 
-// HashPassword hashes a password using bcrypt
+// dummyHash, var olmayan hesaplar için doğrulanan ve hiçbir şifreyle eşleşmeyen hash'tir
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := DefaultHasher.Hash("no account has this password")
+	return hash
+})
+
+// SimulatePasswordCheck takes as long as checking a password with DefaultHasher.
+// Logins for an email without an account call it, so the response time doesn't
+// tell which addresses have accounts.
+func SimulatePasswordCheck(password string) {
+	DefaultHasher.Verify(dummyHash(), password)
+}
+
+// NeedsUpgrade reports whether an encoded hash is not made by DefaultHasher with its current parameters
+func NeedsUpgrade(encoded string) bool {
+	return !DefaultHasher.Matches(encoded) || DefaultHasher.NeedsRehash(encoded)
+}
+
+// HashPassword hashes a password using DefaultHasher
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
+	return DefaultHasher.Hash(password)
+}
+
+func hasherFor(encoded string) Hasher {
+	for _, hasher := range Hashers {
+		if hasher.Matches(encoded) {
+			return hasher
+		}
 	}
-	return string(hashedPassword), nil
+	return nil
 }
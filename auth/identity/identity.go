@@ -0,0 +1,170 @@
+package identity
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"svm/models/db_models"
+)
+
+var (
+	ErrLastIdentity  = errors.New("identity: can't remove the last sign-in method")
+	ErrAlreadyLinked = errors.New("identity: sign-in method is linked to another user")
+)
+
+// NormalizeEmail returns the form email addresses are stored in. Addresses are
+// compared case-insensitively, so John@mail.com and john@mail.com are one account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// PasswordSubject is the subject of a user's password identity
+func PasswordSubject(userID uint) string {
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+// PasskeySubject is the subject of the identity of a WebAuthn credential
+func PasskeySubject(credentialID []byte) string {
+	return base64.RawURLEncoding.EncodeToString(credentialID)
+}
+
+// Find returns the identity of a sign-in method, or gorm.ErrRecordNotFound
+func Find(db *gorm.DB, identityType db_models.IdentityType, issuer, subject string) (db_models.Identity, error) {
+	var identity db_models.Identity
+	err := db.Where("type = ? AND issuer = ? AND subject = ?", identityType, issuer, subject).First(&identity).Error
+	return identity, err
+}
+
+// List returns every identity of the user, oldest first
+func List(db *gorm.DB, userID uint) ([]db_models.Identity, error) {
+	var identities []db_models.Identity
+	err := db.Where("user_id = ?", userID).Order("id").Find(&identities).Error
+	return identities, err
+}
+
+// Link attaches a sign-in method to the user. Linking a method the user already
+// has is a no-op; a method that belongs to someone else fails with ErrAlreadyLinked.
+func Link(db *gorm.DB, userID uint, identityType db_models.IdentityType, issuer, subject, name string) (db_models.Identity, error) {
+	existing, err := Find(db, identityType, issuer, subject)
+	if err == nil {
+		if existing.UserID != userID {
+			return existing, ErrAlreadyLinked
+		}
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return existing, err
+	}
+
+	identity := db_models.Identity{
+		UserID:  userID,
+		Type:    identityType,
+		Issuer:  issuer,
+		Subject: subject,
+		Name:    name,
+	}
+	err = db.Create(&identity).Error
+	return identity, err
+}
+
+// Unlinked describes what Unlink removed
+type Unlinked struct {
+	Identity db_models.Identity
+	// SMSMFADisabled is set when removing the phone also turned off SMS two-factor authentication
+	SMSMFADisabled bool
+}
+
+// Unlink removes one of the user's identities, unless it is the last one. Removing
+// the password identity also clears the password, removing a passkey identity
+// deletes the credential and removing a phone clears the number, so the method
+// really stops working.
+func Unlink(db *gorm.DB, userID, identityID uint) (Unlinked, error) {
+	var unlinked Unlinked
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Aynı anda iki kimliğin kaldırılıp kullanıcının dışarıda kalmaması için satırlar kilitlenir
+		var identities []db_models.Identity
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).Find(&identities).Error; err != nil {
+			return err
+		}
+
+		var target *db_models.Identity
+		for i := range identities {
+			if identities[i].ID == identityID {
+				target = &identities[i]
+			}
+		}
+		if target == nil {
+			return gorm.ErrRecordNotFound
+		}
+		if len(identities) == 1 {
+			return ErrLastIdentity
+		}
+
+		switch target.Type {
+		case db_models.IdentityPassword:
+			if err := tx.Model(&db_models.User{}).Where("id = ?", userID).Update("PasswordHash", "").Error; err != nil {
+				return err
+			}
+		case db_models.IdentityPasskey:
+			credentialID, err := base64.RawURLEncoding.DecodeString(target.Subject)
+			if err != nil {
+				return err
+			}
+			if err := tx.Where("user_id = ? AND credential_id = ?", userID, credentialID).Delete(&db_models.WebAuthnCredential{}).Error; err != nil {
+				return err
+			}
+		case db_models.IdentityPhone:
+			var user db_models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+				return err
+			}
+			// Telefon olmadan SMS ile ikinci adım da çalışamaz
+			if err := tx.Model(&db_models.User{}).Where("id = ? AND phone = ?", userID, target.Subject).Updates(map[string]interface{}{
+				"Phone":           "",
+				"PhoneVerifiedAt": nil,
+				"SMSMFAEnabled":   false,
+			}).Error; err != nil {
+				return err
+			}
+			unlinked.SMSMFADisabled = user.SMSMFAEnabled && user.Phone == target.Subject
+		}
+
+		// Kalıcı silinir, aynı yöntem daha sonra tekrar bağlanabilsin
+		if err := tx.Unscoped().Delete(target).Error; err != nil {
+			return err
+		}
+		unlinked.Identity = *target
+		return nil
+	})
+	return unlinked, err
+}
+
+// Backfill creates the identities of users that signed up before identities
+// existed: one for every password and every passkey
+func Backfill(db *gorm.DB) error {
+	var users []db_models.User
+	if err := db.Where("password_hash <> '' AND NOT EXISTS (SELECT 1 FROM identities WHERE identities.user_id = users.id AND identities.type = ?)", db_models.IdentityPassword).
+		Find(&users).Error; err != nil {
+		return err
+	}
+	for _, user := range users {
+		if _, err := Link(db, user.ID, db_models.IdentityPassword, "", PasswordSubject(user.ID), ""); err != nil {
+			return err
+		}
+	}
+
+	var credentials []db_models.WebAuthnCredential
+	if err := db.Find(&credentials).Error; err != nil {
+		return err
+	}
+	for _, credential := range credentials {
+		if _, err := Link(db, credential.UserID, db_models.IdentityPasskey, "", PasskeySubject(credential.CredentialID), credential.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
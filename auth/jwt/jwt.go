@@ -1,75 +1,322 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"github.com/golang-jwt/jwt/v4"
 	"time"
 )
 
-var jwtSecret = []byte("secret-key") // Bu gizli anahtarı güvenli bir şekilde saklayın
+// TokenType bir token'ın hangi amaçla kullanılabileceğini belirtir
+type TokenType string
+
+const (
+	TokenTypeAccess  TokenType = "access"
+	TokenTypeRefresh TokenType = "refresh"
+	TokenTypeMFA     TokenType = "mfa_challenge"
+
+	TokenTypeEmailVerification TokenType = "email_verification"
+	TokenTypeMagicLink         TokenType = "magic_link"
+)
+
+const (
+	AccessTokenDuration  = time.Minute * 15   // 15 dakika geçerli
+	RefreshTokenDuration = time.Hour * 24 * 7 // 7 gün geçerli
+	MFATokenDuration     = time.Minute * 5    // şifreden sonra ikinci adım için 5 dakika
+
+	EmailVerificationTokenDuration = time.Hour * 24
+	MagicLinkTokenDuration         = time.Minute * 15 // şifresiz giriş linki, tek kullanımlık
+	ImpersonationTokenDuration     = time.Minute * 15 // destek için, yenilenemez
+)
+
+const (
+	Issuer               = "svm"
+	AccessTokenAudience  = "svm-api"
+	RefreshTokenAudience = "svm-auth"
+	MFATokenAudience     = "svm-mfa"
+
+	EmailVerificationAudience = "svm-email"
+	MagicLinkAudience         = "svm-magic-link"
+)
+
+// Scopes granted to access tokens and checked per route by middleware.RequireScopes
+const (
+	ScopeUsersRead      = "users:read"
+	ScopeUsersWrite     = "users:write"
+	ScopeFriendsWrite   = "friends:write"
+	ScopeLocationsWrite = "locations:write"
+	ScopeSessions       = "sessions"
+	ScopeSecurity       = "security" // şifre, iki adımlı doğrulama ve passkey ayarları
+)
+
+// DefaultScopes are granted to every access token issued by Login and RefreshToken
+var DefaultScopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopeFriendsWrite, ScopeLocationsWrite, ScopeSessions, ScopeSecurity}
+
+var (
+	ErrWrongTokenType = errors.New("jwt: wrong token type")
+	ErrWrongAudience  = errors.New("jwt: token audience mismatch")
+	ErrWrongIssuer    = errors.New("jwt: token issuer mismatch")
+)
+
+// Token'ları imzalayan ve doğrulayan anahtar yöneticisi, main içinde SetKeyManager ile ayarlanır
+var keyManager *KeyManager
 
 type Claims struct {
-	UserID  uint     `json:"userId"`
-	Name    string   `json:"name"`
-	Email   string   `json:"email"`
-	Friends []string `json:"friends"`
+	UserID    uint      `json:"userId"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role,omitempty"`
+	Friends   []string  `json:"friends"`
+	TokenType TokenType `json:"token_type"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	SessionID string    `json:"sid,omitempty"`
+	// ImpersonatorID, token bir yönetici tarafından kullanıcı adına alındıysa yöneticinin ID'sidir
+	ImpersonatorID uint `json:"impersonator_id,omitempty"`
+	// DeviceHash, magic link'i isteyen cihaza verilen gizli değerin hash'idir
+	DeviceHash string `json:"device_hash,omitempty"`
+	// FirstFactor, MFA challenge'ında ilk adımın hangi yöntemle geçildiğidir
+	FirstFactor string `json:"first_factor,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// Access Token Oluşturma
-func GenerateAccessToken(userID uint, email, name string, friends []string) (string, error) {
+// HasScope reports whether the token was granted the given scope
+func (c *Claims) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// SetKeyManager sets the keys used by GenerateAccessToken, GenerateRefreshToken and ValidateToken
+func SetKeyManager(km *KeyManager) {
+	keyManager = km
+}
+
+// Access Token Oluşturma, sessionID token'ın ait olduğu refresh token ailesidir
+func GenerateAccessToken(userID uint, email, name, role string, friends []string, sessionID string) (string, error) {
+	return GenerateScopedAccessToken(userID, email, name, role, friends, sessionID, DefaultScopes)
+}
+
+// GenerateScopedAccessToken issues an access token limited to the given scopes
+func GenerateScopedAccessToken(userID uint, email, name, role string, friends []string, sessionID string, scopes []string) (string, error) {
+	registered, err := newRegisteredClaims(TokenTypeAccess, AccessTokenDuration)
+	if err != nil {
+		return "", err
+	}
+
+	claims := &Claims{
+		UserID:           userID,
+		Name:             name,
+		Email:            email,
+		Role:             role,
+		Friends:          friends,
+		TokenType:        TokenTypeAccess,
+		Scopes:           scopes,
+		SessionID:        sessionID,
+		RegisteredClaims: registered,
+	}
+
+	return signClaims(claims)
+}
+
+// APIKeyScopes are the scopes an API key may be granted. Keys can never change security settings or sessions.
+var APIKeyScopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopeFriendsWrite, ScopeLocationsWrite}
+
+// ImpersonationScopes are granted to impersonation tokens; security settings and sessions stay off limits
+var ImpersonationScopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopeFriendsWrite, ScopeLocationsWrite}
+
+// GenerateImpersonationToken issues a short-lived access token for userID on behalf of
+// the administrator impersonatorID. It has no session and cannot be refreshed.
+func GenerateImpersonationToken(userID uint, email, name, role string, friends []string, impersonatorID uint) (string, error) {
+	registered, err := newRegisteredClaims(TokenTypeAccess, ImpersonationTokenDuration)
+	if err != nil {
+		return "", err
+	}
+
 	claims := &Claims{
-		UserID:  userID,
-		Name:    name,
-		Email:   email,
-		Friends: friends,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute * 15)), // 15 dakika geçerli
-			Issuer:    "svm",
-		},
+		UserID:           userID,
+		Name:             name,
+		Email:            email,
+		Role:             role,
+		Friends:          friends,
+		TokenType:        TokenTypeAccess,
+		Scopes:           ImpersonationScopes,
+		ImpersonatorID:   impersonatorID,
+		RegisteredClaims: registered,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return signClaims(claims)
 }
 
 // Refresh Token Oluşturma
 func GenerateRefreshToken(userID uint) (string, error) {
+	registered, err := newRegisteredClaims(TokenTypeRefresh, RefreshTokenDuration)
+	if err != nil {
+		return "", err
+	}
+
 	claims := &Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * 7)), // 7 gün geçerli
-			Issuer:    "svm",
-		},
+		UserID:           userID,
+		TokenType:        TokenTypeRefresh,
+		RegisteredClaims: registered,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return signClaims(claims)
 }
 
-// Token'ı Doğrulama
-func ValidateToken(tokenStr string) (*Claims, error) {
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+// Sign-in methods that can pass the first step of a two-step login
+const (
+	FactorPassword  = "password"
+	FactorMagicLink = "magic_link"
+	FactorOIDC      = "oidc"
+	FactorSMS       = "sms"
+)
+
+// GenerateMFAChallengeToken issues the short-lived token that proves the first step
+// of a two-step login succeeded with firstFactor. It is only accepted by the MFA login
+// step, which must not accept the same factor again.
+func GenerateMFAChallengeToken(userID uint, firstFactor string) (string, error) {
+	registered, err := newRegisteredClaims(TokenTypeMFA, MFATokenDuration)
+	if err != nil {
+		return "", err
+	}
 
-	if err != nil || !token.Valid {
+	claims := &Claims{
+		UserID:           userID,
+		TokenType:        TokenTypeMFA,
+		FirstFactor:      firstFactor,
+		RegisteredClaims: registered,
+	}
+
+	return signClaims(claims)
+}
+
+// GenerateEmailVerificationToken issues the token embedded in the verification link.
+// It carries the address being verified, so the link stops working if the email changes.
+func GenerateEmailVerificationToken(userID uint, email string) (string, error) {
+	registered, err := newRegisteredClaims(TokenTypeEmailVerification, EmailVerificationTokenDuration)
+	if err != nil {
+		return "", err
+	}
+
+	claims := &Claims{
+		UserID:           userID,
+		Email:            email,
+		TokenType:        TokenTypeEmailVerification,
+		RegisteredClaims: registered,
+	}
+
+	return signClaims(claims)
+}
+
+// GenerateMagicLinkToken issues the token embedded in a passwordless login link and
+// returns its jti, which the token store keeps until the link is redeemed. The token
+// is bound to the address and to the device that asked for it through deviceHash.
+func GenerateMagicLinkToken(userID uint, email, deviceHash string) (string, string, error) {
+	registered, err := newRegisteredClaims(TokenTypeMagicLink, MagicLinkTokenDuration)
+	if err != nil {
+		return "", "", err
+	}
+
+	claims := &Claims{
+		UserID:           userID,
+		Email:            email,
+		TokenType:        TokenTypeMagicLink,
+		DeviceHash:       deviceHash,
+		RegisteredClaims: registered,
+	}
+
+	token, err := signClaims(claims)
+	return token, registered.ID, err
+}
+
+// Token'ı Doğrulama, beklenen tür ve audience dışındaki token'lar reddedilir
+func ValidateToken(tokenStr string, tokenType TokenType) (*Claims, error) {
+	claims, err := parseClaims(tokenStr)
+	if err != nil {
 		return nil, err
 	}
 
+	if claims.TokenType != tokenType {
+		return nil, ErrWrongTokenType
+	}
+	if !claims.VerifyAudience(audienceFor(tokenType), true) {
+		return nil, ErrWrongAudience
+	}
+	if !claims.VerifyIssuer(Issuer, true) {
+		return nil, ErrWrongIssuer
+	}
+
 	return claims, nil
 }
 
 // ParseJWT gelen bir JWT'yi parse eder ve claim'leri döner
 func ParseJWT(tokenStr string) (*Claims, error) {
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+	return parseClaims(tokenStr)
+}
+
+func signClaims(claims *Claims) (string, error) {
+	if keyManager == nil {
+		return "", ErrNoKeyManager
+	}
+	return keyManager.Sign(claims)
+}
+
+func audienceFor(tokenType TokenType) string {
+	switch tokenType {
+	case TokenTypeRefresh:
+		return RefreshTokenAudience
+	case TokenTypeMFA:
+		return MFATokenAudience
+	case TokenTypeEmailVerification:
+		return EmailVerificationAudience
+	case TokenTypeMagicLink:
+		return MagicLinkAudience
+	default:
+		return AccessTokenAudience
+	}
+}
+
+func newRegisteredClaims(tokenType TokenType, duration time.Duration) (jwt.RegisteredClaims, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return jwt.RegisteredClaims{}, err
+	}
+
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		ID:        jti,
+		Audience:  jwt.ClaimStrings{audienceFor(tokenType)},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		Issuer:    Issuer,
+	}, nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func parseClaims(tokenStr string) (*Claims, error) {
+	if keyManager == nil {
+		return nil, ErrNoKeyManager
+	}
 
-	if err != nil || !token.Valid {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, keyManager.Keyfunc, jwt.WithValidMethods(SupportedAlgorithms))
+	if err != nil {
 		return nil, err
 	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenUnverifiable
+	}
 
 	return claims, nil
 }
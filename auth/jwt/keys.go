@@ -0,0 +1,321 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	ErrNoKeyManager   = errors.New("jwt: key manager is not configured")
+	ErrNoSigningKey   = errors.New("jwt: no active signing key")
+	ErrUnknownKeyID   = errors.New("jwt: unknown key id")
+	ErrKeyAlgorithm   = errors.New("jwt: token algorithm does not match key")
+	ErrUnsupportedKey = errors.New("jwt: unsupported key type")
+)
+
+// SupportedAlgorithms are the signing algorithms the key manager accepts.
+var SupportedAlgorithms = []string{"RS256", "ES256", "EdDSA"}
+
+// SigningKey is one asymmetric key pair identified by its kid.
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+
+	// retireAt is set once the key has been rotated out. The key keeps
+	// verifying tokens until then and is dropped afterwards.
+	retireAt time.Time
+}
+
+// PublicKey returns the verification half of the key pair.
+func (k *SigningKey) PublicKey() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+// KeyManager holds the active signing key and every key that is still
+// accepted for verification during a rotation window.
+type KeyManager struct {
+	mu     sync.RWMutex
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+func NewKeyManager(active *SigningKey, verifyOnly ...*SigningKey) *KeyManager {
+	km := &KeyManager{keys: make(map[string]*SigningKey)}
+	for _, key := range verifyOnly {
+		km.keys[key.ID] = key
+	}
+	km.keys[active.ID] = active
+	km.active = active
+	return km
+}
+
+// NewKeyManagerFromDir loads every "<kid>.pem" private key in dir. The key
+// with the greatest kid signs new tokens, the others only verify, so a
+// rotation is "add a newer file, delete the old one once its tokens expire".
+// An empty dir falls back to a freshly generated ES256 key.
+func NewKeyManagerFromDir(dir string) (*KeyManager, error) {
+	if dir == "" {
+		key, err := GenerateSigningKey(fmt.Sprintf("ephemeral-%d", time.Now().Unix()), "ES256")
+		if err != nil {
+			return nil, err
+		}
+		return NewKeyManager(key), nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("jwt: no *.pem keys found in %s", dir)
+	}
+	sort.Strings(files)
+
+	var keys []*SigningKey
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		key, err := ParseSigningKeyPEM(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: %s: %w", file, err)
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeyManager(keys[len(keys)-1], keys[:len(keys)-1]...), nil
+}
+
+// GenerateSigningKey creates a new key pair for one of SupportedAlgorithms.
+func GenerateSigningKey(kid, alg string) (*SigningKey, error) {
+	var signer crypto.Signer
+	var err error
+
+	switch alg {
+	case "RS256":
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("jwt: unsupported algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newSigningKey(kid, signer)
+}
+
+// ParseSigningKeyPEM reads a PKCS#8, PKCS#1 or SEC 1 encoded private key.
+func ParseSigningKeyPEM(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt: invalid PEM data")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+	return newSigningKey(kid, signer)
+}
+
+func newSigningKey(kid string, signer crypto.Signer) (*SigningKey, error) {
+	var method jwt.SigningMethod
+
+	switch k := signer.(type) {
+	case *rsa.PrivateKey:
+		method = jwt.SigningMethodRS256
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, ErrUnsupportedKey
+		}
+		method = jwt.SigningMethodES256
+	case ed25519.PrivateKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	return &SigningKey{ID: kid, Method: method, PrivateKey: signer}, nil
+}
+
+// Rotate makes next the signing key. The previous key keeps verifying
+// tokens for the given grace period, which should be at least the lifetime
+// of the longest token it signed.
+func (km *KeyManager) Rotate(next *SigningKey, grace time.Duration) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	if km.active != nil {
+		km.active.retireAt = time.Now().Add(grace)
+	}
+	km.keys[next.ID] = next
+	km.active = next
+}
+
+// Retire stops accepting tokens signed with the given kid immediately.
+func (km *KeyManager) Retire(kid string) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	if km.active != nil && km.active.ID == kid {
+		return
+	}
+	delete(km.keys, kid)
+}
+
+// Sign signs claims with the active key and stamps its kid in the header.
+func (km *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	km.mu.RLock()
+	key := km.active
+	km.mu.RUnlock()
+
+	if key == nil {
+		return "", ErrNoSigningKey
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// Keyfunc resolves the verification key for a token by its kid header.
+func (km *KeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := km.lookup(kid)
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrKeyAlgorithm
+	}
+	return key.PublicKey(), nil
+}
+
+func (km *KeyManager) lookup(kid string) (*SigningKey, bool) {
+	km.mu.RLock()
+	key, ok := km.keys[kid]
+	km.mu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+	if !key.retireAt.IsZero() && time.Now().After(key.retireAt) {
+		km.Retire(kid)
+		return nil, false
+	}
+	return key, true
+}
+
+// verificationKeys returns every key that is still accepted, active first.
+func (km *KeyManager) verificationKeys() []*SigningKey {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	now := time.Now()
+	keys := []*SigningKey{}
+	if km.active != nil {
+		keys = append(keys, km.active)
+	}
+	for _, key := range km.keys {
+		if key == km.active || (!key.retireAt.IsZero() && now.After(key.retireAt)) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// JWK is the RFC 7517 representation of a public key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every key that is still accepted.
+func (km *KeyManager) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range km.verificationKeys() {
+		jwk, err := publicJWK(key)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func publicJWK(key *SigningKey) (JWK, error) {
+	jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+	enc := base64.RawURLEncoding
+
+	switch pub := key.PublicKey().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = enc.EncodeToString(pub.N.Bytes())
+		jwk.E = enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = enc.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = enc.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = enc.EncodeToString(pub)
+	default:
+		return JWK{}, ErrUnsupportedKey
+	}
+	return jwk, nil
+}
@@ -0,0 +1,52 @@
+package oidc
+
+import "errors"
+
+var (
+	ErrNoAccount          = errors.New("oidc: no matching account")
+	ErrEmailNotVerified   = errors.New("oidc: email address is not verified by the identity provider")
+	ErrAccountNotVerified = errors.New("oidc: an account with this email address exists but is not verified")
+)
+
+// Accounts is the user store federated logins sign in to
+type Accounts interface {
+	// FindLinked returns the user the provider account is linked to, or ErrNoAccount
+	FindLinked(issuer, subject string) (uint, error)
+	// FindByEmail returns the user with the email address and whether the user
+	// verified it, or ErrNoAccount
+	FindByEmail(email string) (uint, bool, error)
+	// Link attaches the provider account to the user
+	Link(userID uint, claims *IDTokenClaims) error
+	// Create opens a new account for the provider account and links it
+	Create(claims *IDTokenClaims) (uint, error)
+}
+
+// SignIn returns the local user of a verified ID token. The user is found by the
+// linked provider account, otherwise matched by email address and linked, and
+// created on first login.
+//
+// An email match is only linked when both the provider and the local account have
+// verified the address. Someone may have registered the address first with their
+// own password; linking that account would leave them a way in.
+func SignIn(accounts Accounts, claims *IDTokenClaims) (uint, error) {
+	userID, err := accounts.FindLinked(claims.Issuer, claims.Subject)
+	if err != ErrNoAccount {
+		return userID, err
+	}
+
+	// Doğrulanmamış bir adresle başkasının hesabına bağlanılamasın
+	if claims.Email == "" || !claims.EmailVerified {
+		return 0, ErrEmailNotVerified
+	}
+
+	userID, verified, err := accounts.FindByEmail(claims.Email)
+	switch {
+	case err == ErrNoAccount:
+		return accounts.Create(claims)
+	case err != nil:
+		return 0, err
+	case !verified:
+		return 0, ErrAccountNotVerified
+	}
+	return userID, accounts.Link(userID, claims)
+}
@@ -0,0 +1,71 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidState = errors.New("oidc: invalid or expired login state")
+
+// StateStore keeps a started login until the provider redirects back. ConsumeOIDCLogin
+// must return the data of a state only once.
+type StateStore interface {
+	StoreOIDCLogin(state string, data []byte) error
+	ConsumeOIDCLogin(state string) ([]byte, error)
+}
+
+// pendingLogin begin ve finish arasında saklanır, istemciye hiç gönderilmez
+type pendingLogin struct {
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+	LinkUserID   uint   `json:"link_user_id,omitempty"` // hesap bağlama akışında kimliğin ekleneceği kullanıcı
+}
+
+// BeginLogin starts an authorization code flow with PKCE and returns the URL to send
+// the browser to and the state it comes back with. A non-zero linkUserID starts a
+// flow that links the provider account to that user instead of logging in.
+func (p *Provider) BeginLogin(store StateStore, linkUserID uint) (string, string, error) {
+	state, err := NewState()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := NewState()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		return "", "", err
+	}
+
+	data, err := json.Marshal(pendingLogin{CodeVerifier: verifier, Nonce: nonce, LinkUserID: linkUserID})
+	if err != nil {
+		return "", "", err
+	}
+	if err := store.StoreOIDCLogin(state, data); err != nil {
+		return "", "", err
+	}
+	return p.AuthCodeURL(state, nonce, challenge), state, nil
+}
+
+// FinishLogin consumes the state, exchanges the code with the PKCE verifier of the
+// flow and verifies the ID token against the flow's nonce. A state started for
+// another flow, login or another user's link, fails with ErrInvalidState.
+func (p *Provider) FinishLogin(ctx context.Context, store StateStore, code, state string, linkUserID uint) (*IDTokenClaims, error) {
+	// State tek kullanımlıktır, aynı geri dönüş ikinci kez işlenemez
+	data, err := store.ConsumeOIDCLogin(state)
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+	var login pendingLogin
+	if err := json.Unmarshal(data, &login); err != nil || login.LinkUserID != linkUserID {
+		return nil, ErrInvalidState
+	}
+
+	token, err := p.Exchange(ctx, code, login.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	return p.VerifyIDToken(ctx, token.IDToken, login.Nonce)
+}
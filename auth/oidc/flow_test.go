@@ -0,0 +1,284 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"svm/auth/oidc/oidctest"
+)
+
+const (
+	testClientID     = "svm"
+	testClientSecret = "secret"
+	testRedirectURL  = "http://localhost:8080/oidc/callback"
+)
+
+// memoryStates, TokenStore gibi her state'i bir kez döner
+type memoryStates map[string][]byte
+
+func (states memoryStates) StoreOIDCLogin(state string, data []byte) error {
+	states[state] = data
+	return nil
+}
+
+func (states memoryStates) ConsumeOIDCLogin(state string) ([]byte, error) {
+	data, ok := states[state]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	delete(states, state)
+	return data, nil
+}
+
+type account struct {
+	email    string
+	verified bool
+	linked   []string
+}
+
+// memoryAccounts, kullanıcı tablosu ve bağlı kimlikler yerine geçer
+type memoryAccounts struct {
+	users []*account
+}
+
+func (accounts *memoryAccounts) FindLinked(issuer, subject string) (uint, error) {
+	for i, user := range accounts.users {
+		for _, linked := range user.linked {
+			if linked == issuer+" "+subject {
+				return uint(i + 1), nil
+			}
+		}
+	}
+	return 0, ErrNoAccount
+}
+
+func (accounts *memoryAccounts) FindByEmail(email string) (uint, bool, error) {
+	for i, user := range accounts.users {
+		if user.email == email {
+			return uint(i + 1), user.verified, nil
+		}
+	}
+	return 0, false, ErrNoAccount
+}
+
+func (accounts *memoryAccounts) Link(userID uint, claims *IDTokenClaims) error {
+	user := accounts.users[userID-1]
+	user.linked = append(user.linked, claims.Issuer+" "+claims.Subject)
+	return nil
+}
+
+func (accounts *memoryAccounts) Create(claims *IDTokenClaims) (uint, error) {
+	accounts.users = append(accounts.users, &account{email: claims.Email, verified: true})
+	userID := uint(len(accounts.users))
+	return userID, accounts.Link(userID, claims)
+}
+
+// newTestProvider starts the stand-in provider and a client configured like main.go
+func newTestProvider(t *testing.T) (*oidctest.Provider, *Provider) {
+	t.Helper()
+	idp, err := oidctest.NewProvider(testClientID, testClientSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(idp.Close)
+
+	provider, err := NewProvider(context.Background(), Config{
+		Issuer:       idp.Issuer(),
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		HTTPClient:   idp.Client(),
+	})
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	return idp, provider
+}
+
+// login runs the whole flow: begin, the browser at the provider and the callback
+func login(idp *oidctest.Provider, provider *Provider, states StateStore) (*IDTokenClaims, error) {
+	authorizationURL, state, err := provider.BeginLogin(states, 0)
+	if err != nil {
+		return nil, err
+	}
+	code, returnedState, err := idp.Authorize(authorizationURL)
+	if err != nil {
+		return nil, err
+	}
+	if returnedState != state {
+		return nil, errors.New("provider returned another state")
+	}
+	return provider.FinishLogin(context.Background(), states, code, returnedState, 0)
+}
+
+func TestLoginCreatesAccountOnce(t *testing.T) {
+	idp, provider := newTestProvider(t)
+	accounts := &memoryAccounts{}
+
+	for i := 0; i < 2; i++ {
+		claims, err := login(idp, provider, memoryStates{})
+		if err != nil {
+			t.Fatalf("login %d: %v", i+1, err)
+		}
+		if claims.Issuer != idp.Issuer() || claims.Subject != idp.User.Subject || claims.Email != idp.User.Email {
+			t.Fatalf("login %d: unexpected claims %+v", i+1, claims)
+		}
+
+		userID, err := SignIn(accounts, claims)
+		if err != nil {
+			t.Fatalf("login %d: SignIn: %v", i+1, err)
+		}
+		if userID != 1 || len(accounts.users) != 1 {
+			t.Fatalf("login %d: signed in as %d with %d accounts, want the one created account", i+1, userID, len(accounts.users))
+		}
+	}
+}
+
+func TestLoginLinksAccountWithVerifiedEmail(t *testing.T) {
+	idp, provider := newTestProvider(t)
+	accounts := &memoryAccounts{users: []*account{{email: "jane@mail.com", verified: true}, {email: idp.User.Email, verified: true}}}
+
+	claims, err := login(idp, provider, memoryStates{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := SignIn(accounts, claims)
+	if err != nil {
+		t.Fatalf("SignIn: %v", err)
+	}
+	if userID != 2 || len(accounts.users[1].linked) != 1 {
+		t.Fatalf("signed in as %d, want the existing account 2 linked", userID)
+	}
+}
+
+func TestLoginDoesNotLinkAccountWithUnverifiedEmail(t *testing.T) {
+	idp, provider := newTestProvider(t)
+	// Adresi başkası kendi şifresiyle önceden kaydetmiş olabilir
+	accounts := &memoryAccounts{users: []*account{{email: idp.User.Email}}}
+
+	claims, err := login(idp, provider, memoryStates{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SignIn(accounts, claims); err != ErrAccountNotVerified {
+		t.Fatalf("SignIn: err = %v, want ErrAccountNotVerified", err)
+	}
+	if len(accounts.users[0].linked) != 0 {
+		t.Fatal("provider account was linked to an unverified account")
+	}
+}
+
+func TestLoginRequiresEmailVerifiedByProvider(t *testing.T) {
+	idp, provider := newTestProvider(t)
+	idp.User.EmailVerified = false
+	accounts := &memoryAccounts{users: []*account{{email: idp.User.Email, verified: true}}}
+
+	claims, err := login(idp, provider, memoryStates{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SignIn(accounts, claims); err != ErrEmailNotVerified {
+		t.Fatalf("SignIn: err = %v, want ErrEmailNotVerified", err)
+	}
+}
+
+func TestFinishRejectsReusedState(t *testing.T) {
+	idp, provider := newTestProvider(t)
+	states := memoryStates{}
+
+	authorizationURL, _, err := provider.BeginLogin(states, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state, err := idp.Authorize(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.FinishLogin(context.Background(), states, code, state, 0); err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if _, err := provider.FinishLogin(context.Background(), states, code, state, 0); err != ErrInvalidState {
+		t.Fatalf("replayed callback: err = %v, want ErrInvalidState", err)
+	}
+}
+
+func TestFinishRejectsStateOfAnotherFlow(t *testing.T) {
+	idp, provider := newTestProvider(t)
+	states := memoryStates{}
+
+	// Kullanıcı 7'nin bağlama akışı giriş olarak tamamlanamaz
+	authorizationURL, _, err := provider.BeginLogin(states, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state, err := idp.Authorize(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.FinishLogin(context.Background(), states, code, state, 0); err != ErrInvalidState {
+		t.Fatalf("err = %v, want ErrInvalidState", err)
+	}
+}
+
+func TestFinishRejectsCodeOfAnotherLogin(t *testing.T) {
+	idp, provider := newTestProvider(t)
+	states := memoryStates{}
+
+	// Saldırganın kendi akışından aldığı kod kurbanın state'i ile gönderilir
+	attackerURL, _, err := provider.BeginLogin(states, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attackerCode, _, err := idp.Authorize(attackerURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, victimState, err := provider.BeginLogin(states, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = provider.FinishLogin(context.Background(), states, attackerCode, victimState, 0)
+	if err == nil || err == ErrInvalidState {
+		t.Fatalf("err = %v, want the token endpoint to reject the PKCE verifier", err)
+	}
+}
+
+func TestFinishRejectsNonceMismatch(t *testing.T) {
+	idp, provider := newTestProvider(t)
+	idp.EditClaims = func(claims jwt.MapClaims) {
+		claims["nonce"] = "nonce-of-another-login"
+	}
+
+	if _, err := login(idp, provider, memoryStates{}); err != ErrNonceMismatch {
+		t.Fatalf("err = %v, want ErrNonceMismatch", err)
+	}
+}
+
+func TestFinishRejectsTokenForAnotherClient(t *testing.T) {
+	idp, provider := newTestProvider(t)
+	idp.EditClaims = func(claims jwt.MapClaims) {
+		claims["aud"] = "another-client"
+	}
+
+	if _, err := login(idp, provider, memoryStates{}); err != ErrAudience {
+		t.Fatalf("err = %v, want ErrAudience", err)
+	}
+}
+
+func TestFinishRejectsForgedSignature(t *testing.T) {
+	idp, provider := newTestProvider(t)
+	forger, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.SigningKey = forger
+
+	if _, err := login(idp, provider, memoryStates{}); !errors.Is(err, rsa.ErrVerification) {
+		t.Fatalf("err = %v, want an invalid signature", err)
+	}
+}
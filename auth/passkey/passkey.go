@@ -0,0 +1,181 @@
+package passkey
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"svm/models/db_models"
+)
+
+var (
+	ErrInvalidUserHandle   = errors.New("passkey: invalid user handle")
+	ErrClonedAuthenticator = errors.New("passkey: signature counter went backwards, the authenticator may be cloned")
+)
+
+// Config holds the relying party settings shared by all ceremonies
+type Config struct {
+	RPID          string
+	RPDisplayName string
+	RPOrigins     []string
+}
+
+// New creates the WebAuthn relying party. Passkeys are discoverable and always
+// require user verification, so a passkey login counts as multi-factor.
+func New(config Config) (*webauthn.WebAuthn, error) {
+	residentKey := true
+	return webauthn.New(&webauthn.Config{
+		RPID:          config.RPID,
+		RPDisplayName: config.RPDisplayName,
+		RPOrigins:     config.RPOrigins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			RequireResidentKey: &residentKey,
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			UserVerification:   protocol.VerificationRequired,
+		},
+	})
+}
+
+// FinishRegistration verifies the body of navigator.credentials.create() against the
+// session started by BeginRegistration and returns the new credential
+func FinishRegistration(wa *webauthn.WebAuthn, user *User, session webauthn.SessionData, body io.Reader) (*webauthn.Credential, error) {
+	parsed, err := protocol.ParseCredentialCreationResponseBody(body)
+	if err != nil {
+		return nil, err
+	}
+	// Oturum verisindeki user handle farklı bir kullanıcıya aitse bu adım başarısız olur
+	return wa.CreateCredential(user, session, parsed)
+}
+
+// FinishLogin verifies the body of navigator.credentials.get() against the session
+// started by BeginDiscoverableLogin. loadUser is called with the user the passkey
+// belongs to. An assertion whose signature counter did not increase fails with
+// ErrClonedAuthenticator; otherwise the caller must store the new counter.
+func FinishLogin(wa *webauthn.WebAuthn, session webauthn.SessionData, body io.Reader, loadUser func(userID uint) (*User, error)) (*User, *webauthn.Credential, error) {
+	parsed, err := protocol.ParseCredentialRequestResponseBody(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var user *User
+	credential, err := wa.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, err := UserIDFromHandle(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		user, err = loadUser(userID)
+		return user, err
+	}, session, parsed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Sayaç geriye gittiyse veya aynı kaldıysa anahtar kopyalanmış ya da yanıt tekrar gönderilmiş olabilir
+	if credential.Authenticator.CloneWarning {
+		return nil, nil, ErrClonedAuthenticator
+	}
+	return user, credential, nil
+}
+
+// User adapts a db_models.User and its passkeys to webauthn.User
+type User struct {
+	User        db_models.User
+	Credentials []db_models.WebAuthnCredential
+}
+
+func (u *User) WebAuthnID() []byte {
+	return UserHandle(u.User.ID)
+}
+
+func (u *User) WebAuthnName() string {
+	return u.User.Email
+}
+
+func (u *User) WebAuthnDisplayName() string {
+	return u.User.Name
+}
+
+func (u *User) WebAuthnIcon() string {
+	return ""
+}
+
+func (u *User) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.Credentials))
+	for _, credential := range u.Credentials {
+		credentials = append(credentials, ToWebAuthn(credential))
+	}
+	return credentials
+}
+
+// Exclusions lists the user's existing passkeys so an authenticator is not registered twice
+func (u *User) Exclusions() []protocol.CredentialDescriptor {
+	descriptors := make([]protocol.CredentialDescriptor, 0, len(u.Credentials))
+	for _, credential := range u.WebAuthnCredentials() {
+		descriptors = append(descriptors, credential.Descriptor())
+	}
+	return descriptors
+}
+
+// UserHandle kullanıcı ID'sini WebAuthn user handle'a çevirir, e-posta gibi kişisel veri içermez
+func UserHandle(userID uint) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(userID))
+	return handle
+}
+
+// UserIDFromHandle is the inverse of UserHandle
+func UserIDFromHandle(handle []byte) (uint, error) {
+	if len(handle) != 8 {
+		return 0, ErrInvalidUserHandle
+	}
+	return uint(binary.BigEndian.Uint64(handle)), nil
+}
+
+// ToWebAuthn converts a stored passkey to the library representation
+func ToWebAuthn(credential db_models.WebAuthnCredential) webauthn.Credential {
+	var transports []protocol.AuthenticatorTransport
+	if credential.Transports != "" {
+		for _, transport := range strings.Split(credential.Transports, ",") {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+	}
+
+	return webauthn.Credential{
+		ID:              credential.CredentialID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: credential.BackupEligible,
+			BackupState:    credential.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    credential.AAGUID,
+			SignCount: credential.SignCount,
+		},
+	}
+}
+
+// FromWebAuthn converts a newly registered credential to the stored model
+func FromWebAuthn(userID uint, name string, credential *webauthn.Credential) db_models.WebAuthnCredential {
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	return db_models.WebAuthnCredential{
+		UserID:          userID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      strings.Join(transports, ","),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+}
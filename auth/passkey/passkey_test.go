@@ -0,0 +1,195 @@
+package passkey
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"svm/auth/passkey/passkeytest"
+	"svm/models/db_models"
+)
+
+const testOrigin = "http://localhost:8080"
+
+// testRP, main.go'daki ayarlarla aynı relying party'dir
+func testRP(t *testing.T) *webauthn.WebAuthn {
+	t.Helper()
+	wa, err := New(Config{RPID: "localhost", RPDisplayName: "SVM", RPOrigins: []string{testOrigin}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return wa
+}
+
+// register runs a registration ceremony and returns the user with the stored passkey
+func register(t *testing.T, wa *webauthn.WebAuthn, authenticator *passkeytest.Authenticator) *User {
+	t.Helper()
+	user := &User{User: db_models.User{Name: "John Doe", Email: "john@mail.com"}}
+	user.User.ID = 42
+
+	options, session, err := wa.BeginRegistration(user, webauthn.WithExclusions(user.Exclusions()))
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	body, err := authenticator.Register(options)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	credential, err := FinishRegistration(wa, user, *session, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+
+	// Veritabanına yazılıp okunmuş gibi model üzerinden geçirilir
+	user.Credentials = append(user.Credentials, FromWebAuthn(user.User.ID, "laptop", credential))
+	return user
+}
+
+// login runs an assertion ceremony for the user and stores the new sign count like FinishPasskeyLogin
+func login(wa *webauthn.WebAuthn, authenticator *passkeytest.Authenticator, user *User) (*User, error) {
+	options, session, err := wa.BeginDiscoverableLogin()
+	if err != nil {
+		return nil, err
+	}
+	body, err := authenticator.Login(options)
+	if err != nil {
+		return nil, err
+	}
+	return finishLogin(wa, *session, body, user)
+}
+
+func finishLogin(wa *webauthn.WebAuthn, session webauthn.SessionData, body []byte, user *User) (*User, error) {
+	found, credential, err := FinishLogin(wa, session, bytes.NewReader(body), func(userID uint) (*User, error) {
+		if userID != user.User.ID {
+			return nil, errors.New("unknown user")
+		}
+		return user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range user.Credentials {
+		if bytes.Equal(user.Credentials[i].CredentialID, credential.ID) {
+			user.Credentials[i].SignCount = credential.Authenticator.SignCount
+		}
+	}
+	return found, nil
+}
+
+func TestRegistrationAndLogin(t *testing.T) {
+	wa := testRP(t)
+	authenticator, err := passkeytest.NewAuthenticator(testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := register(t, wa, authenticator)
+	stored := user.Credentials[0]
+	if !bytes.Equal(stored.CredentialID, authenticator.CredentialID) {
+		t.Fatalf("stored credential ID = %x, want %x", stored.CredentialID, authenticator.CredentialID)
+	}
+	if got, err := UserIDFromHandle(authenticator.UserHandle); err != nil || got != user.User.ID {
+		t.Fatalf("user handle resolves to %d (%v), want %d", got, err, user.User.ID)
+	}
+
+	for i := 1; i <= 2; i++ {
+		found, err := login(wa, authenticator, user)
+		if err != nil {
+			t.Fatalf("login %d: %v", i, err)
+		}
+		if found.User.ID != user.User.ID {
+			t.Fatalf("login %d: got user %d, want %d", i, found.User.ID, user.User.ID)
+		}
+		if user.Credentials[0].SignCount != uint32(i) {
+			t.Fatalf("login %d: stored sign count = %d, want %d", i, user.Credentials[0].SignCount, i)
+		}
+	}
+}
+
+func TestRegistrationRejectsWrongOrigin(t *testing.T) {
+	wa := testRP(t)
+	authenticator, err := passkeytest.NewAuthenticator("https://evil.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := &User{User: db_models.User{Name: "John Doe", Email: "john@mail.com"}}
+	user.User.ID = 42
+	options, session, err := wa.BeginRegistration(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := authenticator.Register(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FinishRegistration(wa, user, *session, bytes.NewReader(body)); err == nil {
+		t.Fatal("registration from another origin was accepted")
+	}
+}
+
+func TestLoginRejectsSignCountThatDidNotIncrease(t *testing.T) {
+	wa := testRP(t)
+	authenticator, err := passkeytest.NewAuthenticator(testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := register(t, wa, authenticator)
+
+	if _, err := login(wa, authenticator, user); err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if _, err := login(wa, authenticator, user); err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+
+	// Kopyalanmış bir anahtar eski sayaçla imzalar
+	authenticator.SignCount = 0
+	if _, err := login(wa, authenticator, user); !errors.Is(err, ErrClonedAuthenticator) {
+		t.Fatalf("login with a lower sign count: err = %v, want ErrClonedAuthenticator", err)
+	}
+	if user.Credentials[0].SignCount != 2 {
+		t.Fatalf("stored sign count = %d after rejected login, want 2", user.Credentials[0].SignCount)
+	}
+}
+
+func TestLoginRejectsReusedChallenge(t *testing.T) {
+	wa := testRP(t)
+	authenticator, err := passkeytest.NewAuthenticator(testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := register(t, wa, authenticator)
+
+	options, session, err := wa.BeginDiscoverableLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := authenticator.Login(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := finishLogin(wa, *session, body, user); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	// Aynı yanıt aynı oturumla tekrar gönderilirse sayaç artmamıştır
+	if _, err := finishLogin(wa, *session, body, user); !errors.Is(err, ErrClonedAuthenticator) {
+		t.Fatalf("replayed assertion: err = %v, want ErrClonedAuthenticator", err)
+	}
+
+	// Eski challenge'a verilmiş yanıt yeni bir oturumda kabul edilmez
+	_, newSession, err := wa.BeginDiscoverableLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator.SignCount = 10
+	stale, err := authenticator.Login(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := finishLogin(wa, *newSession, stale, user); err == nil {
+		t.Fatal("assertion for an old challenge was accepted by a new session")
+	}
+}
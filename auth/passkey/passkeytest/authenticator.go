@@ -0,0 +1,156 @@
+// Package passkeytest provides a software WebAuthn authenticator for tests. It
+// answers registration and login options the way a platform authenticator with
+// user verification would, without a browser.
+package passkeytest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+)
+
+// Authenticator holds one ES256 discoverable credential
+type Authenticator struct {
+	Origin       string
+	CredentialID []byte
+	UserHandle   []byte // kayıt sırasında sunucunun verdiği user handle
+	// SignCount is incremented before every assertion. Tests can lower it to
+	// act like a cloned authenticator.
+	SignCount uint32
+
+	key *ecdsa.PrivateKey
+}
+
+// NewAuthenticator creates an authenticator that reports origin in its client data
+func NewAuthenticator(origin string) (*Authenticator, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		return nil, err
+	}
+	return &Authenticator{Origin: origin, CredentialID: credentialID, key: key}, nil
+}
+
+// Register creates the credential for the options of BeginRegistration and returns
+// the body navigator.credentials.create() would produce
+func (a *Authenticator) Register(options *protocol.CredentialCreation) ([]byte, error) {
+	// Kütüphane ayarına göre user handle string ya da URLEncodedBase64 olarak gelir
+	switch id := options.Response.User.ID.(type) {
+	case protocol.URLEncodedBase64:
+		a.UserHandle = id
+	case string:
+		a.UserHandle = []byte(id)
+	}
+
+	clientData, err := a.clientData(protocol.CreateCeremony, options.Response.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1, // P-256
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	authData := a.authData(options.Response.RelyingParty.ID, flagUserPresent|flagUserVerified|flagAttestedCredData)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.CredentialID)))
+	authData = append(authData, a.CredentialID...)
+	authData = append(authData, publicKey...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"id":    encode(a.CredentialID),
+		"rawId": encode(a.CredentialID),
+		"type":  "public-key",
+		"response": map[string]interface{}{
+			"clientDataJSON":    encode(clientData),
+			"attestationObject": encode(attestationObject),
+			"transports":        []string{"internal"},
+		},
+	})
+}
+
+// Login signs the challenge of BeginLogin or BeginDiscoverableLogin and returns
+// the body navigator.credentials.get() would produce
+func (a *Authenticator) Login(options *protocol.CredentialAssertion) ([]byte, error) {
+	clientData, err := a.clientData(protocol.AssertCeremony, options.Response.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	a.SignCount++
+	authData := a.authData(options.Response.RelyingPartyID, flagUserPresent|flagUserVerified)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"id":    encode(a.CredentialID),
+		"rawId": encode(a.CredentialID),
+		"type":  "public-key",
+		"response": map[string]interface{}{
+			"clientDataJSON":    encode(clientData),
+			"authenticatorData": encode(authData),
+			"signature":         encode(signature),
+			"userHandle":        encode(a.UserHandle),
+		},
+	})
+}
+
+func (a *Authenticator) clientData(ceremony protocol.CeremonyType, challenge protocol.URLEncodedBase64) ([]byte, error) {
+	return json.Marshal(protocol.CollectedClientData{
+		Type:      ceremony,
+		Challenge: encode(challenge),
+		Origin:    a.Origin,
+	})
+}
+
+// authData RP ID hash, bayraklar ve imza sayacından oluşan ortak başlıktır
+func (a *Authenticator) authData(rpID string, flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	authData := append([]byte{}, rpIDHash[:]...)
+	authData = append(authData, flags)
+	return binary.BigEndian.AppendUint32(authData, a.SignCount)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
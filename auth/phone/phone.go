@@ -0,0 +1,68 @@
+package phone
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const codeDigits = 6
+
+var ErrInvalidNumber = errors.New("phone: invalid phone number")
+
+// DefaultCountryCode is used for numbers written without a country code, e.g. "0532 123 45 67".
+// Boşsa numaralar + veya 00 ile başlamalıdır.
+var DefaultCountryCode = "90"
+
+// Normalize converts a phone number to E.164 ("+905321234567"). Spaces, dashes,
+// dots and parentheses are ignored; "00" is read as an international prefix and a
+// leading 0 as the national trunk prefix of DefaultCountryCode.
+func Normalize(raw string) (string, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+
+	var digits string
+	switch {
+	case strings.HasPrefix(cleaned, "+"):
+		digits = cleaned[1:]
+	case strings.HasPrefix(cleaned, "00"):
+		digits = cleaned[2:]
+	case DefaultCountryCode != "" && strings.HasPrefix(cleaned, "0"):
+		digits = DefaultCountryCode + cleaned[1:]
+	case DefaultCountryCode != "":
+		digits = DefaultCountryCode + cleaned
+	default:
+		return "", ErrInvalidNumber
+	}
+
+	// E.164: en fazla 15 rakam, ülke kodu 0 ile başlamaz
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", ErrInvalidNumber
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", ErrInvalidNumber
+		}
+	}
+	return "+" + digits, nil
+}
+
+// GenerateCode returns a random numeric one-time code
+func GenerateCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < codeDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", codeDigits, n), nil
+}
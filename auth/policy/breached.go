@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PrefixLength is the number of hex characters of the SHA-1 hash sent to a
+// BreachedSource. Only the prefix leaves the caller (k-anonymity), the rest
+// of the hash is compared locally.
+const PrefixLength = 5
+
+// BreachedSource returns the SHA-1 hash suffixes (upper-case hex, without the
+// prefix) of breached passwords whose hash starts with prefix
+type BreachedSource interface {
+	Range(prefix string) ([]string, error)
+}
+
+// IsBreached reports whether password is known to source
+func IsBreached(source BreachedSource, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:PrefixLength], hash[PrefixLength:]
+
+	suffixes, err := source.Range(prefix)
+	if err != nil {
+		return false, err
+	}
+	for _, candidate := range suffixes {
+		if candidate == suffix {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// BreachedList is an offline BreachedSource kept in memory, grouped by prefix
+type BreachedList struct {
+	ranges map[string][]string
+}
+
+// LoadBreachedList reads a file of SHA-1 password hashes, one per line, in the
+// format of the Have I Been Pwned dumps ("HASH" or "HASH:COUNT").
+// Empty lines and lines starting with # are skipped.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &BreachedList{ranges: make(map[string][]string)}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(text, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("policy: %s:%d: invalid SHA-1 hash", path, line)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("policy: %s:%d: invalid SHA-1 hash", path, line)
+		}
+
+		prefix := hash[:PrefixLength]
+		list.ranges[prefix] = append(list.ranges[prefix], hash[PrefixLength:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Range returns the suffixes stored under prefix
+func (l *BreachedList) Range(prefix string) ([]string, error) {
+	return l.ranges[strings.ToUpper(prefix)], nil
+}
@@ -0,0 +1,170 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Policy describes the rules a new password must satisfy
+type Policy struct {
+	MinLength      int
+	MaxLength      int
+	MinUniqueChars int
+
+	// BannedPatterns reddedilen yaygın kalıplar (klavye dizileri, "password" vb.)
+	BannedPatterns []*regexp.Regexp
+
+	// MinSimilarLength is the shortest part of the user's name or email that
+	// the password may not contain; zero disables the similarity check
+	MinSimilarLength int
+
+	// Breached, if set, is consulted for passwords known from public breaches
+	Breached BreachedSource
+}
+
+// DefaultPolicy is applied wherever a user chooses a new password
+var DefaultPolicy = Policy{
+	MinLength:      8,
+	MaxLength:      128,
+	MinUniqueChars: 4,
+	BannedPatterns: []*regexp.Regexp{
+		regexp.MustCompile(`(?i)passw(o|0)rd`),
+		regexp.MustCompile(`(?i)qwerty|asdfgh|zxcvbn|qwertz`),
+		regexp.MustCompile(`(?i)letmein|welcome|iloveyou|admin`),
+		regexp.MustCompile(`0123|1234|2345|3456|4567|5678|6789|9876|8765|7654|6543|5432|4321|3210`),
+		regexp.MustCompile(`(?i)abcd|bcde|cdef`),
+	},
+	MinSimilarLength: 3,
+}
+
+var ErrPasswordUnchanged = errors.New("new password must be different from the current password")
+
+// Violation codes returned in ValidationError
+const (
+	CodeTooShort      = "too_short"
+	CodeTooLong       = "too_long"
+	CodeTooFewUnique  = "too_few_unique_chars"
+	CodeBannedPattern = "banned_pattern"
+	CodeSimilarToUser = "similar_to_user"
+	CodeBreached      = "breached"
+)
+
+// Violation is one rule the password breaks
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every rule a password breaks
+type ValidationError struct {
+	Message    string      `json:"error"`
+	Violations []Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Check validates password against the policy. userInputs are the user's own
+// details (name, email) the password must not resemble. It returns a
+// *ValidationError for policy violations and a plain error if the breached
+// password source fails.
+func (p Policy) Check(password string, userInputs ...string) error {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, Violation{CodeTooShort, fmt.Sprintf("password must be at least %d characters long", p.MinLength)})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{CodeTooLong, fmt.Sprintf("password must be at most %d characters long", p.MaxLength)})
+	}
+
+	if p.MinUniqueChars > 0 && uniqueChars(password) < p.MinUniqueChars {
+		violations = append(violations, Violation{CodeTooFewUnique, fmt.Sprintf("password must contain at least %d different characters", p.MinUniqueChars)})
+	}
+
+	for _, pattern := range p.BannedPatterns {
+		if pattern.MatchString(password) {
+			violations = append(violations, Violation{CodeBannedPattern, "password contains a common pattern that is easy to guess"})
+			break
+		}
+	}
+
+	if p.MinSimilarLength > 0 && p.similarToUser(password, userInputs) {
+		violations = append(violations, Violation{CodeSimilarToUser, "password must not contain your name or email address"})
+	}
+
+	if p.Breached != nil && length >= p.MinLength {
+		breached, err := IsBreached(p.Breached, password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, Violation{CodeBreached, "password has appeared in a data breach, choose a different one"})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Message: "Password does not meet the policy", Violations: violations}
+	}
+	return nil
+}
+
+func uniqueChars(password string) int {
+	seen := make(map[rune]struct{})
+	for _, r := range password {
+		seen[unicode.ToLower(r)] = struct{}{}
+	}
+	return len(seen)
+}
+
+// similarToUser şifrenin kullanıcının adını, e-postasını veya bunların parçalarını içerip içermediğine bakar
+func (p Policy) similarToUser(password string, userInputs []string) bool {
+	normalized := strings.ToLower(password)
+
+	for _, token := range userTokens(userInputs) {
+		if utf8.RuneCountInString(token) < p.MinSimilarLength {
+			continue
+		}
+		if strings.Contains(normalized, token) || strings.Contains(token, normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+// userTokens splits names on spaces and emails into the full address, the
+// local part and its dot/underscore/dash/plus separated pieces
+func userTokens(userInputs []string) []string {
+	var tokens []string
+	isSeparator := func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("._-+@", r)
+	}
+
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if input == "" {
+			continue
+		}
+
+		if address, err := mail.ParseAddress(input); err == nil {
+			local, _, _ := strings.Cut(address.Address, "@")
+			tokens = append(tokens, address.Address, local)
+			input = local
+		} else {
+			tokens = append(tokens, input)
+		}
+		tokens = append(tokens, strings.FieldsFunc(input, isSeparator)...)
+	}
+	return tokens
+}
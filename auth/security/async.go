@@ -0,0 +1,74 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+var ErrQueueFull = errors.New("security: event queue is full")
+
+// AsyncRecorder hands events to another recorder on a background worker, so the
+// login checks, database writes and alert emails never hold up a request. Events
+// are recorded one at a time in the order they were queued, which the login
+// checks rely on. Events still queued when the process exits without Close are lost.
+type AsyncRecorder struct {
+	next    Recorder
+	timeout time.Duration
+
+	events    chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewAsyncRecorder starts the worker. queueSize events can wait before Record starts
+// dropping them. Each event is recorded with a context that ends after timeout, so
+// a hung database can't stall the queue.
+func NewAsyncRecorder(next Recorder, queueSize int, timeout time.Duration) *AsyncRecorder {
+	recorder := &AsyncRecorder{
+		next:    next,
+		timeout: timeout,
+		events:  make(chan Event, queueSize),
+		done:    make(chan struct{}),
+	}
+	go recorder.run()
+	return recorder
+}
+
+// Record queues the event without waiting for it to be stored. ctx is not used,
+// the event outlives the request it came from.
+func (recorder *AsyncRecorder) Record(ctx context.Context, event Event) error {
+	select {
+	case recorder.events <- event:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close waits until the queued events are recorded. Record must not be called after Close.
+func (recorder *AsyncRecorder) Close() {
+	recorder.closeOnce.Do(func() {
+		close(recorder.events)
+	})
+	<-recorder.done
+}
+
+func (recorder *AsyncRecorder) run() {
+	defer close(recorder.done)
+	for event := range recorder.events {
+		recorder.record(event)
+	}
+}
+
+func (recorder *AsyncRecorder) record(event Event) {
+	// Olay bitmeden sıradakine geçilmez, süre aşımında sorgular iptal edilir
+	ctx, cancel := context.WithTimeout(context.Background(), recorder.timeout)
+	defer cancel()
+
+	if err := recorder.next.Record(ctx, event); err != nil {
+		log.Printf("Failed to record security event %s of user %d: %v", event.Type, event.UserID, err)
+	}
+}
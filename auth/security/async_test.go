@@ -0,0 +1,83 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowRecorder, yanıt vermeyen bir veritabanı gibi ilk olayda context bitene kadar takılır
+type slowRecorder struct {
+	release chan struct{}
+
+	mu       sync.Mutex
+	recorded []EventType
+	errs     []error
+}
+
+func (recorder *slowRecorder) Record(ctx context.Context, event Event) error {
+	var err error
+	if event.Type == EventLogin {
+		select {
+		case <-recorder.release:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.recorded = append(recorder.recorded, event.Type)
+	recorder.errs = append(recorder.errs, err)
+	return err
+}
+
+func TestAsyncRecorderDoesNotWaitForSlowRecorder(t *testing.T) {
+	slow := &slowRecorder{release: make(chan struct{})}
+	defer close(slow.release)
+	recorder := NewAsyncRecorder(slow, 10, 50*time.Millisecond)
+
+	start := time.Now()
+	if err := recorder.Record(context.Background(), Event{Type: EventLogin}); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Record(context.Background(), Event{Type: EventLogout}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Fatalf("Record blocked for %s", elapsed)
+	}
+
+	// Takılan olay süre aşımıyla iptal edilir, sıradaki ancak ondan sonra kaydedilir
+	recorder.Close()
+	slow.mu.Lock()
+	defer slow.mu.Unlock()
+	if len(slow.recorded) != 2 || slow.recorded[0] != EventLogin || slow.recorded[1] != EventLogout {
+		t.Fatalf("recorded %v, want the login and then the logout", slow.recorded)
+	}
+	if !errors.Is(slow.errs[0], context.DeadlineExceeded) {
+		t.Fatalf("stalled login ended with %v, want the deadline to cancel it", slow.errs[0])
+	}
+}
+
+func TestAsyncRecorderDropsEventsWhenQueueIsFull(t *testing.T) {
+	slow := &slowRecorder{release: make(chan struct{})}
+	recorder := NewAsyncRecorder(slow, 1, time.Second)
+
+	// İlk olay işçide takılır, ikincisi kuyrukta bekler
+	recorder.Record(context.Background(), Event{Type: EventLogin})
+	deadline := time.Now().Add(time.Second)
+	for recorder.Record(context.Background(), Event{Type: EventLogout}) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("queue never accepted an event")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := recorder.Record(context.Background(), Event{Type: EventLogout}); err != ErrQueueFull {
+		t.Fatalf("err = %v, want ErrQueueFull", err)
+	}
+
+	close(slow.release)
+	recorder.Close()
+}
@@ -0,0 +1,86 @@
+package security
+
+import (
+	"context"
+	"log"
+
+	"gorm.io/gorm"
+	"svm/models/db_models"
+)
+
+// Notifier tells a user about a suspicious login, e.g. over WebSocket and email
+type Notifier interface {
+	Notify(ctx context.Context, alert Event) error
+}
+
+// DBRecorder stores events in the security_events table. Successful logins are
+// checked for impossible travel and new devices; every finding is stored as an
+// event of its own and handed to the Notifier.
+type DBRecorder struct {
+	DB       *gorm.DB
+	Notifier Notifier // nil ise uyarılar sadece kaydedilir
+}
+
+// NewDBRecorder creates a DBRecorder
+func NewDBRecorder(db *gorm.DB, notifier Notifier) *DBRecorder {
+	return &DBRecorder{DB: db, Notifier: notifier}
+}
+
+// Record stores the event. Queries are run with ctx, so they are cancelled
+// when it is done.
+func (recorder *DBRecorder) Record(ctx context.Context, event Event) error {
+	db := recorder.DB.WithContext(ctx)
+
+	var alerts []Event
+	if event.Type == EventLogin && event.UserID != 0 {
+		var err error
+		// Tespit başarısız olsa da giriş kaydedilmelidir
+		if alerts, err = detectSuspiciousLogin(db, event); err != nil {
+			log.Printf("Failed to check login of user %d: %v", event.UserID, err)
+		}
+	}
+
+	if err := save(db, event); err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		if err := save(db, alert); err != nil {
+			return err
+		}
+		if recorder.Notifier != nil {
+			if err := recorder.Notifier.Notify(ctx, alert); err != nil {
+				log.Printf("Failed to notify user %d of %s: %v", alert.UserID, alert.Type, err)
+			}
+		}
+	}
+	return nil
+}
+
+func save(db *gorm.DB, event Event) error {
+	entry := db_models.SecurityEvent{
+		UserID:     event.UserID,
+		Type:       string(event.Type),
+		Email:      truncate(event.Email, 100),
+		IP:         truncate(event.IP, 64),
+		UserAgent:  truncate(event.UserAgent, 255),
+		DeviceName: truncate(event.DeviceName, 100),
+		Lat:        event.Lat,
+		Lng:        event.Lng,
+		Details:    truncate(event.Details, 500),
+	}
+	entry.CreatedAt = event.CreatedAt
+	return db.Create(&entry).Error
+}
+
+// truncate istemciden gelen alanları sütun boyutuna kısaltır
+func truncate(value string, size int) string {
+	if len(value) <= size {
+		return value
+	}
+	// UTF-8 karakterini ortadan bölmemek için geriye gidilir
+	for size > 0 && value[size]&0xC0 == 0x80 {
+		size--
+	}
+	return value[:size]
+}
@@ -0,0 +1,82 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gorm.io/gorm"
+	"svm/models/db_models"
+)
+
+var (
+	// MaxTravelSpeed is the fastest plausible travel between two logins, in km/h.
+	// A faster move is reported as impossible travel.
+	MaxTravelSpeed = 1000.0
+
+	// MinTravelDistance keeps inaccurate client locations from raising alerts, in km
+	MinTravelDistance = 300.0
+)
+
+const earthRadiusKm = 6371.0
+
+// DistanceKm returns the great-circle distance between two coordinates
+func DistanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// detectSuspiciousLogin compares a successful login with the earlier logins of the
+// user and returns an alert event for each finding. It must run before the login
+// itself is stored.
+func detectSuspiciousLogin(db *gorm.DB, login Event) ([]Event, error) {
+	var alerts []Event
+
+	var previous db_models.SecurityEvent
+	err := db.Where("user_id = ? AND type = ? AND (lat <> 0 OR lng <> 0)", login.UserID, EventLogin).
+		Order("id DESC").First(&previous).Error
+	switch {
+	case err == nil && login.HasLocation():
+		distance := DistanceKm(previous.Lat, previous.Lng, login.Lat, login.Lng)
+		hours := login.CreatedAt.Sub(previous.CreatedAt).Hours()
+		// Aynı anda iki uzak noktadan giriş de imkansız yolculuktur
+		if distance >= MinTravelDistance && (hours <= 0 || distance/hours > MaxTravelSpeed) {
+			alerts = append(alerts, alertFor(login, EventImpossibleTravel,
+				fmt.Sprintf("%.0f km from the login at %s from %s", distance, previous.CreatedAt.Format("2006-01-02 15:04 MST"), previous.IP)))
+		}
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	// İlk girişte her cihaz yenidir, uyarı gönderilmez
+	var logins, sameDevice int64
+	if err := db.Model(&db_models.SecurityEvent{}).Where("user_id = ? AND type = ?", login.UserID, EventLogin).
+		Count(&logins).Error; err != nil {
+		return nil, err
+	}
+	if logins > 0 {
+		if err := db.Model(&db_models.SecurityEvent{}).
+			Where("user_id = ? AND type = ? AND user_agent = ? AND device_name = ?", login.UserID, EventLogin, truncate(login.UserAgent, 255), truncate(login.DeviceName, 100)).
+			Count(&sameDevice).Error; err != nil {
+			return nil, err
+		}
+		if sameDevice == 0 {
+			alerts = append(alerts, alertFor(login, EventNewDevice, "login from a device not seen before"))
+		}
+	}
+
+	return alerts, nil
+}
+
+// alertFor şüpheli girişin bilgileriyle bir uyarı olayı oluşturur
+func alertFor(login Event, alertType EventType, details string) Event {
+	alert := login
+	alert.Type = alertType
+	alert.Details = details
+	return alert
+}
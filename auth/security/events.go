@@ -0,0 +1,91 @@
+package security
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// EventType identifies a security relevant action on an account
+type EventType string
+
+const (
+	EventLogin           EventType = "login"
+	EventLoginFailed     EventType = "login_failed"
+	EventLoginLockout    EventType = "login_lockout"
+	EventTokenRefresh    EventType = "token_refresh"
+	EventLogout          EventType = "logout"
+	EventPasswordChanged EventType = "password_changed"
+	EventPasswordReset   EventType = "password_reset"
+	EventMFAEnabled      EventType = "mfa_enabled"
+	EventMFADisabled     EventType = "mfa_disabled"
+
+	// Şüpheli girişler için üretilen uyarılar
+	EventImpossibleTravel EventType = "impossible_travel"
+	EventNewDevice        EventType = "new_device"
+)
+
+// Event is one security relevant action. UserID is zero when the action
+// could not be tied to an existing user, e.g. failures for an unknown email.
+// Lat and Lng are the coordinates the client reported; both zero means unknown.
+type Event struct {
+	Type       EventType
+	UserID     uint
+	Email      string
+	IP         string
+	UserAgent  string
+	DeviceName string
+	Lat        float64
+	Lng        float64
+	Details    string
+	CreatedAt  time.Time
+}
+
+// HasLocation reports whether the client sent coordinates with the event
+func (event Event) HasLocation() bool {
+	return event.Lat != 0 || event.Lng != 0
+}
+
+// Recorder stores or forwards security events. Work done for the event should
+// stop once ctx is done.
+type Recorder interface {
+	Record(ctx context.Context, event Event) error
+}
+
+// LogRecorder writes events to the standard logger
+type LogRecorder struct{}
+
+func (LogRecorder) Record(ctx context.Context, event Event) error {
+	log.Printf("security event %s: user=%d email=%q ip=%s user_agent=%q %s",
+		event.Type, event.UserID, event.Email, event.IP, event.UserAgent, event.Details)
+	return nil
+}
+
+var (
+	mu       sync.RWMutex
+	recorder Recorder = LogRecorder{}
+)
+
+// SetRecorder replaces the recorder used by Record
+func SetRecorder(r Recorder) {
+	mu.Lock()
+	defer mu.Unlock()
+	recorder = r
+}
+
+// Record stamps the event time if missing and hands the event to the configured
+// recorder. Failures are logged, recording must never break the request.
+func Record(event Event) {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	mu.RLock()
+	r := recorder
+	mu.RUnlock()
+
+	if err := r.Record(context.Background(), event); err != nil {
+		log.Printf("Failed to record security event %s: %v", event.Type, err)
+	}
+}
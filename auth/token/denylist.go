@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RevokeAccessToken adds a single access token to the denylist until it expires
+func (store *TokenStore) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		// Süresi dolmuş token zaten geçersiz
+		return nil
+	}
+	return store.RedisClient.Set(ctx, createRevokedJTIKey(jti), 1, ttl).Err()
+}
+
+// RevokeUserAccessTokens rejects every access token of the user issued up to now.
+// ttl should be the access token lifetime, after which those tokens expire anyway.
+func (store *TokenStore) RevokeUserAccessTokens(userID uint, ttl time.Duration) error {
+	return store.RedisClient.Set(ctx, createRevokedUserKey(userID), time.Now().Unix(), ttl).Err()
+}
+
+// IsAccessTokenRevoked checks the jti and session denylists and the per-user revocation time
+func (store *TokenStore) IsAccessTokenRevoked(userID uint, sessionID, jti string, issuedAt time.Time) (bool, error) {
+	values, err := store.RedisClient.MGet(ctx,
+		createRevokedJTIKey(jti),
+		createRevokedSessionKey(sessionID),
+		createRevokedUserKey(userID),
+	).Result()
+	if err != nil && err != redis.Nil {
+		return false, err
+	}
+
+	if values[0] != nil || (sessionID != "" && values[1] != nil) {
+		return true, nil
+	}
+	if revokedAt, ok := values[2].(string); ok {
+		cutoff, err := strconv.ParseInt(revokedAt, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		// Aynı saniye içinde üretilen token'lar da iptal sayılır
+		if issuedAt.Unix() <= cutoff {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func createRevokedJTIKey(jti string) string {
+	return "revoked_jti:" + jti
+}
+
+func createRevokedUserKey(userID uint) string {
+	return "revoked_user:" + strconv.FormatUint(uint64(userID), 10)
+}
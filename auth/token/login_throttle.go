@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ThrottlePolicy describes how failed logins slow down and finally lock out one account or IP
+type ThrottlePolicy struct {
+	FreeAttempts     int64         // failures allowed before any delay
+	BaseDelay        time.Duration // first delay, doubled on every further failure
+	MaxDelay         time.Duration
+	LockoutThreshold int64 // failures that trigger a lockout
+	LockoutDuration  time.Duration
+	Window           time.Duration // failures are forgotten after this long without a new one
+}
+
+// AccountThrottle is applied per email address
+var AccountThrottle = ThrottlePolicy{
+	FreeAttempts:     3,
+	BaseDelay:        time.Second,
+	MaxDelay:         time.Minute * 5,
+	LockoutThreshold: 10,
+	LockoutDuration:  time.Minute * 15,
+	Window:           time.Minute * 15,
+}
+
+// IPThrottle is applied per client IP; it is looser because NAT puts many users behind one address
+var IPThrottle = ThrottlePolicy{
+	FreeAttempts:     20,
+	BaseDelay:        time.Second,
+	MaxDelay:         time.Minute * 5,
+	LockoutThreshold: 100,
+	LockoutDuration:  time.Minute * 15,
+	Window:           time.Minute * 15,
+}
+
+// ThrottleResult is the state of one counter after a failed login
+type ThrottleResult struct {
+	Failures   int64
+	RetryAfter time.Duration
+	// LockedOut is true only for the failure that triggered the lockout
+	LockedOut bool
+}
+
+// LoginRetryAfter returns how long the client has to wait before the next
+// login attempt for email from ip, zero if it may try now
+func (store *TokenStore) LoginRetryAfter(email, ip string) (time.Duration, error) {
+	pipe := store.RedisClient.Pipeline()
+	accountTTL := pipe.PTTL(ctx, createLoginBlockKey("account", normalizeEmail(email)))
+	ipTTL := pipe.PTTL(ctx, createLoginBlockKey("ip", ip))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, err
+	}
+
+	// Anahtar yoksa PTTL negatif döner
+	wait := accountTTL.Val()
+	if ipTTL.Val() > wait {
+		wait = ipTTL.Val()
+	}
+	if wait < 0 {
+		return 0, nil
+	}
+	return wait, nil
+}
+
+// RecordLoginFailure counts a failed login against both the account and the IP
+func (store *TokenStore) RecordLoginFailure(email, ip string) (account ThrottleResult, client ThrottleResult, err error) {
+	account, err = store.recordFailure(AccountThrottle, "account", normalizeEmail(email))
+	if err != nil {
+		return account, client, err
+	}
+	client, err = store.recordFailure(IPThrottle, "ip", ip)
+	return account, client, err
+}
+
+// ResetLoginFailures clears the failure counter and any lockout of an account.
+// It runs after a successful login and when an administrator unlocks the account.
+func (store *TokenStore) ResetLoginFailures(email string) error {
+	email = normalizeEmail(email)
+	return store.RedisClient.Del(ctx, createLoginFailuresKey("account", email), createLoginBlockKey("account", email)).Err()
+}
+
+func (store *TokenStore) recordFailure(policy ThrottlePolicy, kind, id string) (ThrottleResult, error) {
+	var failures *redis.IntCmd
+	_, err := store.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		failures = pipe.Incr(ctx, createLoginFailuresKey(kind, id))
+		pipe.Expire(ctx, createLoginFailuresKey(kind, id), policy.Window)
+		return nil
+	})
+	if err != nil {
+		return ThrottleResult{}, err
+	}
+
+	result := ThrottleResult{Failures: failures.Val()}
+	switch {
+	case result.Failures >= policy.LockoutThreshold:
+		result.RetryAfter = policy.LockoutDuration
+		result.LockedOut = result.Failures == policy.LockoutThreshold
+	case result.Failures > policy.FreeAttempts:
+		// Her başarısız denemede bekleme süresi ikiye katlanır
+		result.RetryAfter = policy.BaseDelay << (result.Failures - policy.FreeAttempts - 1)
+		if result.RetryAfter <= 0 || result.RetryAfter > policy.MaxDelay {
+			result.RetryAfter = policy.MaxDelay
+		}
+	}
+
+	if result.RetryAfter > 0 {
+		if err := store.RedisClient.Set(ctx, createLoginBlockKey(kind, id), result.Failures, result.RetryAfter).Err(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func createLoginFailuresKey(kind, id string) string {
+	return "login_failures:" + kind + ":" + id
+}
+
+func createLoginBlockKey(kind, id string) string {
+	return "login_block:" + kind + ":" + id
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var ErrMagicLinkNotFound = errors.New("magic link not found, already used or expired")
+
+// StoreMagicLink marks the link with the given token ID as redeemable. Requesting
+// a new link invalidates the previous one of the same user.
+func (store *TokenStore) StoreMagicLink(userID uint, jti string, ttl time.Duration) error {
+	userKey := createMagicLinkUserKey(userID)
+	previous, err := store.RedisClient.Get(ctx, userKey).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	_, err = store.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previous != "" {
+			pipe.Del(ctx, createMagicLinkKey(previous))
+		}
+		pipe.Set(ctx, createMagicLinkKey(jti), userID, ttl)
+		pipe.Set(ctx, userKey, jti, ttl)
+		return nil
+	})
+	return err
+}
+
+// ConsumeMagicLink returns the user of a link and deletes it, so a replayed link is rejected
+func (store *TokenStore) ConsumeMagicLink(jti string) (uint, error) {
+	userID, err := store.RedisClient.GetDel(ctx, createMagicLinkKey(jti)).Uint64()
+	if err == redis.Nil {
+		return 0, ErrMagicLinkNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	store.RedisClient.Del(ctx, createMagicLinkUserKey(uint(userID)))
+	return uint(userID), nil
+}
+
+func createMagicLinkKey(jti string) string {
+	return "magic_link:" + jti
+}
+
+func createMagicLinkUserKey(userID uint) string {
+	return "magic_link_user:" + strconv.FormatUint(uint64(userID), 10)
+}
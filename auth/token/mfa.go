@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxMFAAttempts is how many wrong codes one MFA challenge accepts before it is burned
+const MaxMFAAttempts = 5
+
+var ErrMFAChallengeUsed = errors.New("mfa challenge already used or exhausted")
+
+// RecordMFAAttempt counts an attempt against the challenge and fails once the
+// challenge has been completed or has run out of attempts.
+func (store *TokenStore) RecordMFAAttempt(jti string, ttl time.Duration) error {
+	key := createMFAChallengeKey(jti)
+	attempts, err := store.RedisClient.Incr(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if attempts == 1 {
+		store.RedisClient.Expire(ctx, key, ttl)
+	}
+	if attempts > MaxMFAAttempts {
+		return ErrMFAChallengeUsed
+	}
+	return nil
+}
+
+// CompleteMFAChallenge makes the challenge unusable after a successful login
+func (store *TokenStore) CompleteMFAChallenge(jti string, ttl time.Duration) error {
+	return store.RedisClient.Set(ctx, createMFAChallengeKey(jti), MaxMFAAttempts+1, ttl).Err()
+}
+
+func createMFAChallengeKey(jti string) string {
+	return "mfa_challenge:" + jti
+}
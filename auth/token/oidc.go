@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// OIDCLoginDuration is how long a started federated login can be finished
+const OIDCLoginDuration = time.Minute * 10
+
+var ErrOIDCLoginNotFound = errors.New("oidc login not found or expired")
+
+// StoreOIDCLogin saves the PKCE verifier and nonce of a federated login under its state
+func (store *TokenStore) StoreOIDCLogin(state string, data []byte) error {
+	return store.RedisClient.Set(ctx, createOIDCLoginKey(state), data, OIDCLoginDuration).Err()
+}
+
+// ConsumeOIDCLogin returns the saved login once, so a state value can't be replayed
+func (store *TokenStore) ConsumeOIDCLogin(state string) ([]byte, error) {
+	data, err := store.RedisClient.GetDel(ctx, createOIDCLoginKey(state)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrOIDCLoginNotFound
+	}
+	return data, err
+}
+
+func createOIDCLoginKey(state string) string {
+	return "oidc_login:" + state
+}
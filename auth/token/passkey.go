@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PasskeyCeremonyDuration is how long a started WebAuthn ceremony can be finished
+const PasskeyCeremonyDuration = time.Minute * 5
+
+var ErrCeremonyNotFound = errors.New("passkey ceremony not found or expired")
+
+// StorePasskeyCeremony saves the WebAuthn session data between the begin and finish calls
+// and returns the ID the client sends back to finish the ceremony.
+func (store *TokenStore) StorePasskeyCeremony(data []byte) (string, error) {
+	id, err := NewSessionID()
+	if err != nil {
+		return "", err
+	}
+	if err := store.RedisClient.Set(ctx, createPasskeyCeremonyKey(id), data, PasskeyCeremonyDuration).Err(); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// ConsumePasskeyCeremony returns the session data once, so a challenge can't be replayed
+func (store *TokenStore) ConsumePasskeyCeremony(id string) ([]byte, error) {
+	data, err := store.RedisClient.GetDel(ctx, createPasskeyCeremonyKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrCeremonyNotFound
+	}
+	return data, err
+}
+
+func createPasskeyCeremonyKey(id string) string {
+	return "passkey_ceremony:" + id
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PasswordResetDuration is how long a password reset link stays valid
+const PasswordResetDuration = time.Minute * 30
+
+var ErrPasswordResetNotFound = errors.New("password reset token not found or expired")
+
+// StorePasswordResetToken saves the hash of a reset token. Requesting a new
+// reset invalidates the previous link of the same user.
+func (store *TokenStore) StorePasswordResetToken(userID uint, tokenHash string) error {
+	userKey := createPasswordResetUserKey(userID)
+	previous, err := store.RedisClient.Get(ctx, userKey).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	_, err = store.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previous != "" {
+			pipe.Del(ctx, createPasswordResetKey(previous))
+		}
+		pipe.Set(ctx, createPasswordResetKey(tokenHash), userID, PasswordResetDuration)
+		pipe.Set(ctx, userKey, tokenHash, PasswordResetDuration)
+		return nil
+	})
+	return err
+}
+
+// LookupPasswordResetToken returns the user of a reset token without using it up,
+// so the new password can be validated before the link is spent
+func (store *TokenStore) LookupPasswordResetToken(tokenHash string) (uint, error) {
+	userID, err := store.RedisClient.Get(ctx, createPasswordResetKey(tokenHash)).Uint64()
+	if err == redis.Nil {
+		return 0, ErrPasswordResetNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return uint(userID), nil
+}
+
+// ConsumePasswordResetToken returns the user of a reset token and deletes it, so each link works once
+func (store *TokenStore) ConsumePasswordResetToken(tokenHash string) (uint, error) {
+	userID, err := store.RedisClient.GetDel(ctx, createPasswordResetKey(tokenHash)).Uint64()
+	if err == redis.Nil {
+		return 0, ErrPasswordResetNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	store.RedisClient.Del(ctx, createPasswordResetUserKey(uint(userID)))
+	return uint(userID), nil
+}
+
+func createPasswordResetKey(tokenHash string) string {
+	return "password_reset:" + tokenHash
+}
+
+func createPasswordResetUserKey(userID uint) string {
+	return "password_reset_user:" + strconv.FormatUint(uint64(userID), 10)
+}
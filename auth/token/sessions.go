@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	authJWT "svm/auth/jwt"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionInfo describes the device that started or refreshed a session
+type SessionInfo struct {
+	DeviceName string
+	UserAgent  string
+	IP         string
+}
+
+// Session is one refresh token family of a user, i.e. one logged-in device
+type Session struct {
+	ID         string
+	DeviceName string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
+
+// ListSessions kullanıcının aktif oturumlarını döner, süresi dolmuş olanları indeksten temizler
+func (store *TokenStore) ListSessions(userID uint) ([]Session, error) {
+	userSessionsKey := createUserSessionsKey(userID)
+	familyIDs, err := store.RedisClient.SMembers(ctx, userSessionsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []Session{}
+	for _, familyID := range familyIDs {
+		values, err := store.RedisClient.HGetAll(ctx, createFamilyKey(familyID)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			store.RedisClient.SRem(ctx, userSessionsKey, familyID)
+			continue
+		}
+
+		sessions = append(sessions, Session{
+			ID:         familyID,
+			DeviceName: values["device_name"],
+			UserAgent:  values["user_agent"],
+			IP:         values["ip"],
+			CreatedAt:  parseUnix(values["created_at"]),
+			LastUsedAt: parseUnix(values["last_used_at"]),
+		})
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession kullanıcının tek bir oturumunu kapatır
+func (store *TokenStore) RevokeSession(userID uint, sessionID string) error {
+	owner, err := store.RedisClient.HGet(ctx, createFamilyKey(sessionID), "user_id").Result()
+	if err == redis.Nil {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if owner != strconv.FormatUint(uint64(userID), 10) {
+		return ErrSessionNotFound
+	}
+
+	return store.RevokeRefreshFamily(sessionID)
+}
+
+// RevokeAllSessions closes every session of the user except keepSessionID, which may be empty
+func (store *TokenStore) RevokeAllSessions(userID uint, keepSessionID string) error {
+	familyIDs, err := store.RedisClient.SMembers(ctx, createUserSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, familyID := range familyIDs {
+		if familyID == keepSessionID {
+			continue
+		}
+		if err := store.RevokeRefreshFamily(familyID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *TokenStore) revokeSessionAccessTokens(sessionID string) error {
+	return store.RedisClient.Set(ctx, createRevokedSessionKey(sessionID), 1, authJWT.AccessTokenDuration).Err()
+}
+
+func createRevokedSessionKey(sessionID string) string {
+	return "revoked_session:" + sessionID
+}
+
+func parseUnix(value string) time.Time {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
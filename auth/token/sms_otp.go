@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SMSOTPPurpose keeps codes sent for one flow from being used in another
+type SMSOTPPurpose string
+
+const (
+	SMSOTPRegister SMSOTPPurpose = "register"
+	SMSOTPLogin    SMSOTPPurpose = "login"
+	SMSOTPVerify   SMSOTPPurpose = "verify" // mevcut hesaba numara ekleme
+	SMSOTPMFA      SMSOTPPurpose = "mfa"
+)
+
+const (
+	SMSOTPDuration       = time.Minute * 5
+	SMSOTPResendInterval = time.Minute // aynı numaraya bu süre dolmadan yeni kod gönderilmez
+	MaxSMSOTPAttempts    = 5
+)
+
+var (
+	ErrSMSOTPTooSoon = errors.New("a code was sent to this number recently")
+	ErrSMSOTPInvalid = errors.New("sms code is invalid, expired or used up")
+)
+
+// StoreSMSOTP saves the hash of a code sent to phone, replacing any earlier code of
+// the same purpose. It fails with ErrSMSOTPTooSoon while the resend interval runs.
+func (store *TokenStore) StoreSMSOTP(purpose SMSOTPPurpose, phone, codeHash string) error {
+	sent, err := store.RedisClient.SetNX(ctx, createSMSOTPCooldownKey(phone), 1, SMSOTPResendInterval).Result()
+	if err != nil {
+		return err
+	}
+	if !sent {
+		return ErrSMSOTPTooSoon
+	}
+
+	key := createSMSOTPKey(purpose, phone)
+	_, err = store.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, "hash", codeHash, "attempts", 0)
+		pipe.Expire(ctx, key, SMSOTPDuration)
+		return nil
+	})
+	return err
+}
+
+// VerifySMSOTP checks a code and deletes it on success, so it works once. Every
+// attempt counts; after MaxSMSOTPAttempts the code is burned.
+func (store *TokenStore) VerifySMSOTP(purpose SMSOTPPurpose, phone, codeHash string) error {
+	key := createSMSOTPKey(purpose, phone)
+	attempts, err := store.RedisClient.HIncrBy(ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return err
+	}
+	stored, err := store.RedisClient.HGet(ctx, key, "hash").Result()
+	if err == redis.Nil {
+		// HIncrBy olmayan anahtarı oluşturmuş olabilir
+		store.RedisClient.Del(ctx, key)
+		return ErrSMSOTPInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	if attempts > MaxSMSOTPAttempts {
+		store.RedisClient.Del(ctx, key)
+		return ErrSMSOTPInvalid
+	}
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(codeHash)) != 1 {
+		return ErrSMSOTPInvalid
+	}
+
+	// Aynı kod eşzamanlı iki istekte kullanılamasın, sadece silen kazanır
+	deleted, err := store.RedisClient.Del(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrSMSOTPInvalid
+	}
+	return nil
+}
+
+func createSMSOTPKey(purpose SMSOTPPurpose, phone string) string {
+	return "sms_otp:" + string(purpose) + ":" + phone
+}
+
+func createSMSOTPCooldownKey(phone string) string {
+	return "sms_otp_cooldown:" + phone
+}
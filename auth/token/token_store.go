@@ -2,6 +2,10 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -9,6 +13,11 @@ import (
 
 var ctx = context.Background()
 
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenReused   = errors.New("refresh token reuse detected")
+)
+
 type TokenStore struct {
 	RedisClient *redis.Client
 }
@@ -21,26 +30,176 @@ func NewTokenStore(redisAddr string) *TokenStore {
 	return &TokenStore{RedisClient: rdb}
 }
 
-// Refresh Token'ı Redis'te saklama (kullanıcı ID ile birlikte)
-func (store *TokenStore) StoreRefreshToken(userID uint, token string, duration time.Duration) error {
+// Refresh Token'ı Redis'te saklama (kullanıcı ID ile birlikte).
+// Her login yeni bir token ailesi, yani yeni bir oturum başlatır ve oturumun ID'si döner.
+func (store *TokenStore) StoreRefreshToken(userID uint, token string, duration time.Duration, info SessionInfo) (string, error) {
+	familyID, err := NewSessionID()
+	if err != nil {
+		return "", err
+	}
+
 	key := createRedisKey(userID, token)
-	return store.RedisClient.Set(ctx, key, userID, duration).Err()
+	familyKey := createFamilyKey(familyID)
+	userSessionsKey := createUserSessionsKey(userID)
+	now := time.Now().Unix()
+
+	_, err = store.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "user_id", userID, "family", familyID)
+		pipe.Expire(ctx, key, duration)
+		pipe.HSet(ctx, familyKey,
+			"current", key,
+			"user_id", userID,
+			"device_name", info.DeviceName,
+			"user_agent", info.UserAgent,
+			"ip", info.IP,
+			"created_at", now,
+			"last_used_at", now,
+		)
+		pipe.Expire(ctx, familyKey, duration)
+		pipe.SAdd(ctx, userSessionsKey, familyID)
+		pipe.Expire(ctx, userSessionsKey, duration)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return familyID, nil
 }
 
 // Refresh Token'ı Redis'ten çekme
 func (store *TokenStore) FetchRefreshToken(userID uint, token string) (uint, error) {
 	var key string
 	key = createRedisKey(userID, token)
-	storedUserID, err := store.RedisClient.Get(ctx, key).Uint64()
+	storedUserID, err := store.RedisClient.HGet(ctx, key, "user_id").Uint64()
 	return uint(storedUserID), err
 }
 
-// Refresh Token'ı Redis'ten silme
+// Refresh Token'ı Redis'ten silme, token'ın ailesi de kapatılır
 func (store *TokenStore) DeleteRefreshToken(userID uint, token string) error {
 	key := createRedisKey(userID, token)
+	familyID, err := store.RedisClient.HGet(ctx, key, "family").Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if familyID != "" {
+		return store.RevokeRefreshFamily(familyID)
+	}
 	return store.RedisClient.Del(ctx, key).Err()
 }
 
+// rotateScript eski token'ı siler, "rotated" olarak işaretler ve aynı ailede yeni token'ı kaydeder.
+// Daha önce döndürülmüş bir token gelirse ailesini "reused" ile birlikte döner.
+var rotateScript = redis.NewScript(`
+local family = redis.call('HGET', KEYS[1], 'family')
+if not family then
+	local rotated = redis.call('GET', KEYS[2])
+	if rotated then
+		return {'reused', rotated}
+	end
+	return {'missing', ''}
+end
+if redis.call('HGET', KEYS[1], 'user_id') ~= ARGV[1] then
+	return {'missing', ''}
+end
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl <= 0 then
+	ttl = tonumber(ARGV[2])
+end
+local familyKey = ARGV[3] .. family
+redis.call('DEL', KEYS[1])
+redis.call('SET', KEYS[2], family, 'PX', ttl)
+redis.call('HSET', KEYS[3], 'user_id', ARGV[1], 'family', family)
+redis.call('PEXPIRE', KEYS[3], ARGV[2])
+redis.call('HSET', familyKey, 'current', KEYS[3], 'last_used_at', ARGV[4], 'ip', ARGV[5])
+redis.call('PEXPIRE', familyKey, ARGV[2])
+redis.call('PEXPIRE', KEYS[4], ARGV[2])
+return {'ok', family}
+`)
+
+// RotateRefreshToken replaces oldToken with newToken inside the same family and
+// returns the family's session ID. Presenting a token that was already rotated
+// revokes the whole family and returns ErrRefreshTokenReused.
+func (store *TokenStore) RotateRefreshToken(userID uint, oldToken, newToken string, duration time.Duration, info SessionInfo) (string, error) {
+	keys := []string{
+		createRedisKey(userID, oldToken),
+		createRotatedKey(userID, oldToken),
+		createRedisKey(userID, newToken),
+		createUserSessionsKey(userID),
+	}
+	result, err := rotateScript.Run(ctx, store.RedisClient, keys,
+		strconv.FormatUint(uint64(userID), 10), duration.Milliseconds(), familyKeyPrefix,
+		time.Now().Unix(), info.IP).StringSlice()
+	if err != nil {
+		return "", err
+	}
+
+	switch result[0] {
+	case "ok":
+		return result[1], nil
+	case "reused":
+		if err := store.RevokeRefreshFamily(result[1]); err != nil {
+			return "", err
+		}
+		return "", ErrRefreshTokenReused
+	default:
+		return "", ErrRefreshTokenNotFound
+	}
+}
+
+// RevokeRefreshFamily ailenin geçerli refresh token'ını, aile kaydını ve
+// bu oturuma ait access token'ları iptal eder
+func (store *TokenStore) RevokeRefreshFamily(familyID string) error {
+	familyKey := createFamilyKey(familyID)
+	family, err := store.RedisClient.HMGet(ctx, familyKey, "current", "user_id").Result()
+	if err != nil {
+		return err
+	}
+
+	keys := []string{familyKey}
+	if currentKey, ok := family[0].(string); ok {
+		keys = append(keys, currentKey)
+	}
+
+	_, err = store.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, keys...)
+		if userID, ok := family[1].(string); ok {
+			pipe.SRem(ctx, userSessionsKeyPrefix+userID, familyID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return store.revokeSessionAccessTokens(familyID)
+}
+
+const (
+	familyKeyPrefix       = "refresh_family:"
+	userSessionsKeyPrefix = "user_sessions:"
+)
+
 func createRedisKey(userID uint, token string) string {
-	return "refresh_token:" + token
+	return "refresh_token:" + strconv.FormatUint(uint64(userID), 10) + ":" + token
+}
+
+func createRotatedKey(userID uint, token string) string {
+	return "refresh_rotated:" + strconv.FormatUint(uint64(userID), 10) + ":" + token
+}
+
+func createFamilyKey(familyID string) string {
+	return familyKeyPrefix + familyID
+}
+
+func createUserSessionsKey(userID uint) string {
+	return userSessionsKeyPrefix + strconv.FormatUint(uint64(userID), 10)
+}
+
+// NewSessionID returns a random identifier for a refresh token family
+func NewSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// WebSocketTicketDuration is how long a WebSocket connection ticket can be redeemed
+const WebSocketTicketDuration = time.Second * 30
+
+var ErrWebSocketTicketNotFound = errors.New("websocket ticket not found or expired")
+
+// StoreWebSocketTicket issues a single-use ticket that lets userID open one
+// WebSocket connection. Only the ticket's hash is stored.
+func (store *TokenStore) StoreWebSocketTicket(userID uint) (string, error) {
+	ticket, ticketHash, err := NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	if err := store.RedisClient.Set(ctx, createWebSocketTicketKey(ticketHash), userID, WebSocketTicketDuration).Err(); err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+// ConsumeWebSocketTicket returns the user of a ticket and deletes it, so each ticket opens one connection
+func (store *TokenStore) ConsumeWebSocketTicket(ticket string) (uint, error) {
+	userID, err := store.RedisClient.GetDel(ctx, createWebSocketTicketKey(HashOpaqueToken(ticket))).Uint64()
+	if err == redis.Nil {
+		return 0, ErrWebSocketTicketNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return uint(userID), nil
+}
+
+func createWebSocketTicketKey(ticketHash string) string {
+	return "ws_ticket:" + ticketHash
+}
@@ -0,0 +1,31 @@
+package verification
+
+import (
+	"fmt"
+	"net/url"
+	authJWT "svm/auth/jwt"
+	"svm/mail"
+	"svm/models/db_models"
+)
+
+// Sender emails signed, expiring verification links
+type Sender struct {
+	Mailer  mail.Mailer
+	BaseURL string // Linklerin işaret ettiği adres, ör. http://localhost:8080
+}
+
+// Send emails a verification link for the user's current address
+func (s *Sender) Send(user db_models.User) error {
+	token, err := authJWT.GenerateEmailVerificationToken(user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+
+	link := s.BaseURL + "/api/email/verify?token=" + url.QueryEscape(token)
+	return s.Mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+			user.Name, link, authJWT.EmailVerificationTokenDuration),
+	})
+}
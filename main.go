@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -13,11 +19,20 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 	authhandlers "svm/api/auth"
 	"svm/api/user"
+	authJWT "svm/auth/jwt"
+	"svm/auth/oidc"
+	"svm/auth/passkey"
+	"svm/auth/phone"
+	"svm/auth/policy"
+	"svm/auth/security"
 	authToken "svm/auth/token"
+	"svm/auth/verification"
 	_ "svm/docs" // Swagger documentation
+	"svm/mail"
 	smvmmidlleware "svm/middleware"
 	"svm/migrations"
 	"svm/models/db_models"
+	"svm/sms"
 )
 
 // @title MyApp API
@@ -39,25 +54,127 @@ import (
 // @in header
 // @name Authorization
 func main() {
+	promoteAdmin := flag.String("promote-admin", "", "give the admin role to the user with this verified email address and exit")
+	flag.Parse()
+
 	db, err := migrations.CreateDb()
 	if err != nil {
 		log.Fatalf("Failed to create database connection: %v", err)
 	}
 
+	// İlk yönetici tek seferlik bir komutla atanır, sunucu her açılışta kimseyi yönetici yapmaz:
+	// go run . -promote-admin=admin@example.com
+	if *promoteAdmin != "" {
+		if err := migrations.PromoteAdmin(db, *promoteAdmin); err != nil {
+			log.Fatalf("Failed to promote admin user: %v", err)
+		}
+		log.Printf("%s is now an admin", *promoteAdmin)
+		return
+	}
+
+	// JWT_KEYS_DIR boşsa her açılışta yeni bir ES256 anahtarı üretilir
+	keyManager, err := authJWT.NewKeyManagerFromDir(os.Getenv("JWT_KEYS_DIR"))
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+	authJWT.SetKeyManager(keyManager)
+
 	tokenStore := authToken.NewTokenStore("localhost:6379")
+
+	// Doğrulama linkleri için SMTP sunucusu (geliştirmede ör. MailHog)
+	mailer := mail.NewSMTPMailer("localhost", 1025, "", "", "no-reply@myapp.com")
+	sender := &verification.Sender{
+		Mailer:  mailer,
+		BaseURL: "http://localhost:8080",
+	}
+	authhandlers.RequireVerifiedEmail = os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true"
+
+	// Güvenlik olayları veritabanına yazılır, şüpheli girişler kullanıcıya WebSocket ve e-posta ile bildirilir.
+	// Kayıt arka planda yapılır, yavaş bir SMTP sunucusu girişleri bekletmesin.
+	securityRecorder := security.NewAsyncRecorder(
+		security.NewDBRecorder(db, &authhandlers.SecurityAlertNotifier{DB: db, Mailer: mailer}),
+		1000, 30*time.Second,
+	)
+	security.SetRecorder(securityRecorder)
+
+	// Gerçek bir SMS sağlayıcısı bağlanana kadar kodlar log'a yazılır
+	smsSender := sms.NewLogSender()
+	if code, ok := os.LookupEnv("PHONE_DEFAULT_COUNTRY_CODE"); ok {
+		phone.DefaultCountryCode = code
+	}
+
+	// Sızdırılmış şifre listesi (HIBP formatında SHA-1 hash'ler) verilmişse yeni şifreler bununla da kontrol edilir
+	if path := os.Getenv("BREACHED_PASSWORDS_FILE"); path != "" {
+		breached, err := policy.LoadBreachedList(path)
+		if err != nil {
+			log.Fatalf("Failed to load breached password list: %v", err)
+		}
+		policy.DefaultPolicy.Breached = breached
+	}
+
+	// İstemci IP'si sadece bu proxy'lerin gönderdiği X-Forwarded-For'dan okunur, boşsa başlıklar yok sayılır
+	trustedProxies, err := smvmmidlleware.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatalf("Failed to parse TRUSTED_PROXIES: %v", err)
+	}
+
+	// Tarayıcı istemcileri için cookie tabanlı oturum, istemci X-Session-Mode: cookie ile seçer
+	smvmmidlleware.Cookies.Enabled = os.Getenv("SESSION_COOKIES") == "true"
+	smvmmidlleware.Cookies.AccessToken = os.Getenv("SESSION_COOKIES_ACCESS_TOKEN") == "true"
+	smvmmidlleware.Cookies.Domain = os.Getenv("COOKIE_DOMAIN")
+
+	// Cookie'ler credentials ile gönderildiğinden origin'ler tek tek listelenmeli, "*" kabul edilmez
+	allowedOrigins := []string{"https://example.com"} // Update with your allowed origins
+	if origins := os.Getenv("CORS_ALLOWED_ORIGINS"); origins != "" {
+		allowedOrigins = strings.Split(origins, ",")
+	}
+	for i, origin := range allowedOrigins {
+		allowedOrigins[i] = strings.TrimSpace(origin)
+		if allowedOrigins[i] == "*" {
+			log.Fatal("CORS_ALLOWED_ORIGINS must list origins explicitly when credentials are allowed")
+		}
+	}
+
+	webAuthn, err := passkey.New(passkey.Config{
+		RPID:          "localhost",
+		RPDisplayName: "SVM",
+		RPOrigins:     []string{"http://localhost:8080"}, // Update with your allowed origins
+	})
+	if err != nil {
+		log.Fatalf("Failed to configure WebAuthn: %v", err)
+	}
+	// OIDC_ISSUER verilmişse dış kimlik sağlayıcısı ile giriş açılır
+	var oidcProvider *oidc.Provider
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		oidcProvider, err = oidc.NewProvider(context.Background(), oidc.Config{
+			Issuer:       issuer,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure OIDC provider: %v", err)
+		}
+	}
+
 	m := melody.New()
+	// Access token ile bağlanan istemciler "bearer" subprotocol'ünü seçer
+	m.Upgrader.Subprotocols = []string{authhandlers.WebSocketSubprotocol}
 
 	// Melody WebSocket handlers
 	m.HandleConnect(handleWsCon())
 	m.HandleDisconnect(handleWsDisc())
 	m.HandleMessage(func(s *melody.Session, msg []byte) {
-		userID := s.Request.URL.Query().Get("user_id")
+		userID, ok := authhandlers.WebSocketUserID(s)
+		if !ok {
+			return
+		}
 
 		var user db_models.User
 		db.Preload("Friends").First(&user, userID)
 
 		for _, friend := range user.Friends {
-			if session, ok := authhandlers.UserSessions[fmt.Sprintf("%d", friend.ID)]; ok {
+			if session, ok := authhandlers.UserSessions.Get(fmt.Sprintf("%d", friend.ID)); ok {
 				session.Write(msg)
 			}
 		}
@@ -67,75 +184,179 @@ func main() {
 
 	// Middlewares
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(smvmmidlleware.RealIP(trustedProxies))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
 
 	// CORS middleware using rs/cors package
 	corsMiddleware := cors.New(cors.Options{
-		AllowedOrigins:   []string{"https://example.com"}, // Update with your allowed origins
+		AllowedOrigins:   allowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", smvmmidlleware.APIKeyHeader, smvmmidlleware.CSRFHeader, smvmmidlleware.SessionModeHeader},
+		ExposedHeaders:   []string{"Link", "Retry-After", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not to be preflighted
 	})
 
 	// Applying the CORS middleware to the router
 	r.Use(corsMiddleware.Handler)
+	// Cookie ile gelen değiştirici istekler X-CSRF-Token header'ı taşımalı
+	r.Use(smvmmidlleware.CSRFProtection)
+
+	// Rate limit policies, declared per route below
+	rateLimit := func(name string, limit int, window time.Duration, key smvmmidlleware.RateLimitKeyFunc, methods ...string) func(http.Handler) http.Handler {
+		return smvmmidlleware.RateLimit(tokenStore, smvmmidlleware.RateLimitPolicy{Name: name, Limit: limit, Window: window, Key: key, Methods: methods})
+	}
+	loginLimit := rateLimit("login", 10, time.Minute, smvmmidlleware.KeyByIP)
+	registerLimit := rateLimit("register", 5, time.Hour, smvmmidlleware.KeyByIP)
+	emailLimit := rateLimit("email", 5, time.Minute*15, smvmmidlleware.KeyByIP)  // e-posta gönderen uçlar
+	tokenLimit := rateLimit("token", 10, time.Minute*15, smvmmidlleware.KeyByIP) // tek kullanımlık link token'larının denendiği uçlar
+	smsLimit := rateLimit("sms", 5, time.Minute*15, smvmmidlleware.KeyByIP)      // SMS gönderen uçlar
+	refreshLimit := rateLimit("refresh", 30, time.Minute, smvmmidlleware.KeyByIP)
+	publicReadLimit := rateLimit("public_read", 300, time.Minute, smvmmidlleware.KeyByIP, http.MethodGet, http.MethodHead) // kimlik doğrulaması olmayan uçlar
+	readLimit := rateLimit("read", 300, time.Minute, smvmmidlleware.KeyByAPIKey, http.MethodGet, http.MethodHead)
+	writeLimit := rateLimit("write", 60, time.Minute, smvmmidlleware.KeyByAPIKey, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete)
 
 	// Public routes
 	r.Get("/swagger/*", httpSwagger.WrapHandler)
-	r.Post("/api/login", authhandlers.Login(db, tokenStore, m))
-	r.Post("/api/refresh-token", authhandlers.RefreshToken(db, tokenStore))
+	r.Get("/.well-known/jwks.json", authhandlers.JWKS(keyManager))
+	r.With(loginLimit).Post("/api/login", authhandlers.Login(db, tokenStore, m))
+	r.With(loginLimit).Post("/api/login/mfa", authhandlers.LoginMFA(db, tokenStore, m))
+	r.With(emailLimit).Post("/api/login/magic", authhandlers.RequestMagicLink(db, tokenStore, mailer, "https://example.com/magic-login"))
+	r.With(loginLimit).Post("/api/login/magic/verify", authhandlers.LoginWithMagicLink(db, tokenStore, m))
+	r.With(smsLimit).Post("/api/login/mfa/sms", authhandlers.SendMFASMSCode(db, tokenStore, smsSender))
+	r.With(smsLimit).Post("/api/phone/register/begin", authhandlers.BeginPhoneRegistration(db, tokenStore, smsSender))
+	r.With(registerLimit).Post("/api/phone/register/finish", authhandlers.FinishPhoneRegistration(db, tokenStore, m))
+	r.With(smsLimit).Post("/api/phone/login/begin", authhandlers.BeginPhoneLogin(db, tokenStore, smsSender))
+	r.With(loginLimit).Post("/api/phone/login/finish", authhandlers.FinishPhoneLogin(db, tokenStore, m))
+	if oidcProvider != nil {
+		r.With(loginLimit).Post("/api/oidc/login/begin", authhandlers.BeginOIDCLogin(tokenStore, oidcProvider))
+		r.With(loginLimit).Post("/api/oidc/login/finish", authhandlers.FinishOIDCLogin(db, tokenStore, m, oidcProvider))
+	}
+	r.With(loginLimit).Post("/api/passkeys/login/begin", authhandlers.BeginPasskeyLogin(tokenStore, webAuthn))
+	r.With(loginLimit).Post("/api/passkeys/login/finish", authhandlers.FinishPasskeyLogin(db, tokenStore, m, webAuthn))
+	r.With(refreshLimit).Post("/api/refresh-token", authhandlers.RefreshToken(db, tokenStore))
 	r.Post("/api/logout", authhandlers.Logout(tokenStore))
-	r.Get("/api/online-users", authhandlers.GetOnlineUsers(tokenStore))
-	r.Post("/api/register", user.CreateUser(db))
+	r.With(publicReadLimit).Get("/api/online-users", authhandlers.GetOnlineUsers(tokenStore))
+	r.With(registerLimit).Post("/api/register", user.CreateUser(db, sender))
+	r.With(tokenLimit).Get("/api/email/verify", authhandlers.VerifyEmail(db))
+	r.With(emailLimit).Post("/api/email/verify/resend", authhandlers.ResendVerificationEmail(db, sender))
+	r.With(emailLimit).Post("/api/password/forgot", authhandlers.ForgotPassword(db, tokenStore, mailer, "https://example.com/reset-password"))
+	r.With(tokenLimit).Post("/api/password/reset", authhandlers.ResetPassword(db, tokenStore))
 
 	// Protected routes
 	r.Group(func(r chi.Router) {
-		r.Use(smvmmidlleware.JWTAuthentication)
+		r.Use(smvmmidlleware.APIKeyAuthentication(db))
+		r.Use(smvmmidlleware.JWTAuthentication(tokenStore))
+		r.Use(readLimit, writeLimit)
 		r.Route("/api/users", func(r chi.Router) {
-			r.Put("/{id}", user.UpdateUser(db))
-			r.Get("/", user.ListUsers(db))
-			r.Delete("/{id}", user.DeleteUser(db))
-			r.Get("/{id}", user.GetUserByID(db))
-			r.Post("/friends", user.AddFriend(db))
-			r.Post("/location", user.AddUserLocation(db))
+			r.With(smvmmidlleware.RequireScopes(authJWT.ScopeUsersWrite)).Put("/{id}", user.UpdateUser(db))
+			r.With(smvmmidlleware.RequireScopes(authJWT.ScopeUsersRead)).Get("/", user.ListUsers(db))
+			r.With(smvmmidlleware.RequireScopes(authJWT.ScopeUsersWrite)).Delete("/{id}", user.DeleteUser(db, tokenStore))
+			r.With(smvmmidlleware.RequireScopes(authJWT.ScopeUsersRead)).Get("/{id}", user.GetUserByID(db))
+			r.With(smvmmidlleware.RequireScopes(authJWT.ScopeFriendsWrite)).Post("/friends", user.AddFriend(db))
+			r.With(smvmmidlleware.RequireScopes(authJWT.ScopeUsersRead)).Get("/friends/requests", user.ListFriendRequests(db))
+			r.With(smvmmidlleware.RequireScopes(authJWT.ScopeFriendsWrite)).Post("/friends/requests/{id}/accept", user.AcceptFriendRequest(db))
+			r.With(smvmmidlleware.RequireScopes(authJWT.ScopeFriendsWrite)).Delete("/friends/requests/{id}", user.DeleteFriendRequest(db))
+			r.With(smvmmidlleware.RequireScopes(authJWT.ScopeLocationsWrite)).Post("/location", user.AddUserLocation(db))
+		})
+		r.Route("/api/sessions", func(r chi.Router) {
+			r.Use(smvmmidlleware.RequireScopes(authJWT.ScopeSessions))
+			r.Get("/", authhandlers.ListSessions(tokenStore))
+			r.Delete("/{id}", authhandlers.RevokeSession(tokenStore))
+			r.Post("/revoke-all", authhandlers.RevokeAllSessions(tokenStore))
+		})
+		r.With(smvmmidlleware.RequireScopes(authJWT.ScopeSecurity)).Post("/api/password/change", authhandlers.ChangePassword(db, tokenStore))
+		r.Route("/api/mfa/totp", func(r chi.Router) {
+			r.Use(smvmmidlleware.RequireScopes(authJWT.ScopeSecurity))
+			r.Post("/enroll", authhandlers.EnrollTOTP(db))
+			r.Post("/verify", authhandlers.VerifyTOTP(db))
+			r.Post("/disable", authhandlers.DisableTOTP(db))
+		})
+		r.Route("/api/mfa/sms", func(r chi.Router) {
+			r.Use(smvmmidlleware.RequireScopes(authJWT.ScopeSecurity))
+			r.With(smsLimit).Post("/code", authhandlers.SendSMSCode(db, tokenStore, smsSender))
+			r.Post("/enable", authhandlers.EnableSMSMFA(db, tokenStore))
+			r.Post("/disable", authhandlers.DisableSMSMFA(db, tokenStore))
+		})
+		r.Route("/api/phone", func(r chi.Router) {
+			r.Use(smvmmidlleware.RequireScopes(authJWT.ScopeSecurity))
+			r.With(smsLimit).Post("/", authhandlers.SendPhoneVerification(db, tokenStore, smsSender))
+			r.Post("/verify", authhandlers.VerifyPhone(db, tokenStore))
+		})
+		r.Route("/api/passkeys", func(r chi.Router) {
+			r.Use(smvmmidlleware.RequireScopes(authJWT.ScopeSecurity))
+			r.Get("/", authhandlers.ListPasskeys(db))
+			r.Delete("/{id}", authhandlers.DeletePasskey(db))
+			r.Post("/register/begin", authhandlers.BeginPasskeyRegistration(db, tokenStore, webAuthn))
+			r.Post("/register/finish", authhandlers.FinishPasskeyRegistration(db, tokenStore, webAuthn))
+		})
+		r.Route("/api/identities", func(r chi.Router) {
+			r.Use(smvmmidlleware.RequireScopes(authJWT.ScopeSecurity))
+			r.Get("/", authhandlers.ListIdentities(db))
+			r.Delete("/{id}", authhandlers.UnlinkIdentity(db))
+			r.Post("/password", authhandlers.LinkPassword(db))
+			if oidcProvider != nil {
+				r.Post("/oidc/begin", authhandlers.BeginOIDCLink(tokenStore, oidcProvider))
+				r.Post("/oidc/finish", authhandlers.FinishOIDCLink(db, tokenStore, oidcProvider))
+			}
+		})
+		r.With(smvmmidlleware.RequireScopes(authJWT.ScopeSecurity)).Get("/api/security/events", authhandlers.ListSecurityEvents(db))
+		r.With(smvmmidlleware.RequireScopes(authJWT.ScopeLocationsWrite)).Post("/api/ws/ticket", authhandlers.CreateWebSocketTicket(tokenStore))
+		r.Route("/api/api-keys", func(r chi.Router) {
+			r.Use(smvmmidlleware.RequireScopes(authJWT.ScopeSecurity))
+			r.Get("/", authhandlers.ListAPIKeys(db))
+			r.Post("/", authhandlers.CreateAPIKey(db))
+			r.Delete("/{id}", authhandlers.RevokeAPIKey(db))
+		})
+		r.Route("/api/admin", func(r chi.Router) {
+			// API anahtarları ve impersonation token'ları security scope'u taşımaz, yönetici uçlarına erişemez
+			r.Use(smvmmidlleware.RequireScopes(authJWT.ScopeSecurity), smvmmidlleware.RequireRole(db_models.RoleAdmin))
+			r.Get("/users", authhandlers.SearchUsers(db))
+			r.Post("/users/{id}/suspend", authhandlers.SuspendUser(db, tokenStore))
+			r.Post("/users/{id}/unsuspend", authhandlers.UnsuspendUser(db))
+			r.Post("/users/{id}/logout", authhandlers.ForceLogout(db, tokenStore))
+			r.Post("/users/{id}/mfa/reset", authhandlers.ResetMFA(db))
+			r.Post("/users/{id}/impersonate", authhandlers.Impersonate(db))
+			r.Post("/users/{id}/unlock", authhandlers.UnlockAccount(db, tokenStore))
+			r.Get("/audit", authhandlers.ListAuditLogs(db))
 		})
 	})
 
 	// WebSocket endpoint
-	r.Get("/ws", handleWSConnection(m))
+	r.Get("/ws", authhandlers.WebSocket(m, tokenStore))
 
-	log.Fatal(http.ListenAndServe(":8080", r))
-}
-
-func handleWSConnection(m *melody.Melody) func(w http.ResponseWriter, r *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if err := m.HandleRequest(w, r); err != nil {
-			log.Println(err)
+	server := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
 		}
+	}()
+
+	// Kapanırken yarım kalan istekler beklenir, kuyruktaki güvenlik olayları kaybolmasın diye yazılır
+	stop, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	<-stop.Done()
+
+	shutdown, cancelShutdown := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdown); err != nil {
+		log.Printf("Failed to shut down the server: %v", err)
 	}
+	securityRecorder.Close()
 }
 
 func handleWsCon() func(s *melody.Session) {
 	return func(s *melody.Session) {
-		userID := s.Request.URL.Query().Get("user_id")
-		if userID != "" {
-			authhandlers.UserSessions[userID] = s
+		if userID, ok := authhandlers.WebSocketUserID(s); ok {
+			authhandlers.UserSessions.Set(userID, s)
 		}
 	}
 }
 
 func handleWsDisc() func(s *melody.Session) {
 	return func(s *melody.Session) {
-		for userID, session := range authhandlers.UserSessions {
-			if session == s {
-				delete(authhandlers.UserSessions, userID)
-				break
-			}
-		}
+		authhandlers.UserSessions.RemoveSession(s)
 	}
 }
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"gorm.io/gorm"
+	"net/http"
+	"svm/auth/authz"
+	authToken "svm/auth/token"
+	"svm/models/db_models"
+	"time"
+)
+
+// apiKeyUsageInterval limits how often LastUsedAt is written for a busy key
+const apiKeyUsageInterval = time.Minute
+
+// APIKeyAuthentication authenticates requests that carry an API key in the
+// X-API-Key header and stores the same principal JWTAuthentication would.
+// Requests without the header are passed on untouched, so it is meant to run
+// right before JWTAuthentication.
+func APIKeyAuthentication(db *gorm.DB) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(APIKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var apiKey db_models.APIKey
+			if err := db.Where("key_hash = ? AND revoked_at IS NULL", authToken.HashOpaqueToken(key)).First(&apiKey).Error; err != nil {
+				http.Error(w, "Invalid or expired API key", http.StatusUnauthorized)
+				return
+			}
+
+			now := time.Now()
+			if now.After(apiKey.ExpiresAt) {
+				http.Error(w, "Invalid or expired API key", http.StatusUnauthorized)
+				return
+			}
+
+			// Askıya alınan veya silinen kullanıcının anahtarları da çalışmaz
+			var user db_models.User
+			if err := db.First(&user, apiKey.UserID).Error; err != nil || user.SuspendedAt != nil {
+				http.Error(w, "Invalid or expired API key", http.StatusUnauthorized)
+				return
+			}
+
+			if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyUsageInterval {
+				db.Model(&apiKey).Update("LastUsedAt", now)
+			}
+
+			r = r.WithContext(authz.WithPrincipal(r.Context(), authz.PrincipalFromAPIKey(apiKey, user)))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
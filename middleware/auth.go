@@ -1,39 +1,147 @@
 package middleware
 
 import (
-	"context"
+	"errors"
 	"net/http"
 	"strings"
+	"svm/auth/authz"
 	auth "svm/auth/jwt"
+	authToken "svm/auth/token"
+	"svm/models/db_models"
+	"time"
 )
 
-func JWTAuthentication(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
-			return
-		}
-
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
-			return
-		}
-
-		tokenString := parts[1]
-		claims, err := auth.ValidateToken(tokenString)
-		if err != nil {
-			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
-			return
-		}
-
-		// Token geçerli, kullanıcı ID'sini isteğin context'ine ekleyebiliriz
-		ctx := r.Context()
-		ctx = context.WithValue(ctx, "userID", claims.UserID)
-		r = r.WithContext(ctx)
-
-		// Sonraki middleware veya handler'a geç
-		next.ServeHTTP(w, r)
-	})
+// JWTAuthentication validates the access token and rejects tokens on the denylist in tokenStore.
+// Requests already authenticated by APIKeyAuthentication pass through.
+func JWTAuthentication(tokenStore *authToken.TokenStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// APIKeyAuthentication isteği zaten doğruladıysa token aranmaz
+			if _, ok := authz.PrincipalFromRequest(r); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			authHeader := r.Header.Get("Authorization")
+			tokenString, ok := BearerToken(r)
+			if authHeader == "" {
+				// Tarayıcı oturumunda access token cookie'den gelir, CSRFProtection header'ı kontrol eder
+				tokenString, ok = AccessTokenFromCookie(r)
+				if !ok {
+					http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
+					return
+				}
+			} else if !ok {
+				http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := AuthenticateAccessToken(tokenStore, tokenString)
+			switch {
+			case errors.Is(err, ErrInvalidToken):
+				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			case errors.Is(err, ErrTokenRevoked):
+				http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+				return
+			case err != nil:
+				http.Error(w, "Failed to check token revocation", http.StatusInternalServerError)
+				return
+			}
+
+			// Token geçerli, kimliği doğrulanmış kullanıcı isteğin context'ine eklenir
+			r = r.WithContext(authz.WithPrincipal(r.Context(), authz.PrincipalFromClaims(claims)))
+
+			// Sonraki middleware veya handler'a geç
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+var (
+	ErrInvalidToken = errors.New("invalid or expired token")
+	ErrTokenRevoked = errors.New("token has been revoked")
+)
+
+// AuthenticateAccessToken validates an access token and checks it against the
+// denylist. Errors other than ErrInvalidToken and ErrTokenRevoked come from tokenStore.
+func AuthenticateAccessToken(tokenStore *authToken.TokenStore, tokenString string) (*auth.Claims, error) {
+	// Sadece access token kabul edilir, refresh token burada geçersizdir
+	claims, err := auth.ValidateToken(tokenString, auth.TokenTypeAccess)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Logout veya şifre değişikliği ile iptal edilmiş token'ları reddet
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revoked, err := tokenStore.IsAccessTokenRevoked(claims.UserID, claims.SessionID, claims.ID, issuedAt)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// BearerToken returns the token from an "Authorization: Bearer <token>" header
+func BearerToken(r *http.Request) (string, bool) {
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// RequireScopes rejects requests whose access token lacks any of the given scopes.
+// It must run after JWTAuthentication.
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := authz.PrincipalFromRequest(r)
+			if !ok {
+				http.Error(w, "Missing authentication", http.StatusUnauthorized)
+				return
+			}
+
+			for _, scope := range scopes {
+				if !principal.HasScope(scope) {
+					w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+					http.Error(w, "Insufficient scope", http.StatusForbidden)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole rejects requests whose access token is below the given role.
+// It must run after JWTAuthentication.
+func RequireRole(role db_models.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := authz.PrincipalFromRequest(r)
+			if !ok {
+				http.Error(w, "Missing authentication", http.StatusUnauthorized)
+				return
+			}
+
+			if !principal.HasRole(role) {
+				Forbidden(w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Forbidden writes the 403 response used for every failed role or ownership check
+func Forbidden(w http.ResponseWriter) {
+	http.Error(w, "Forbidden", http.StatusForbidden)
 }
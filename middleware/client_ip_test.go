@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		wantIP       string
+		wantKeyByIP  string
+	}{
+		{name: "direct client ignores headers", remoteAddr: "203.0.113.7:5000", forwardedFor: []string{"1.2.3.4"}, realIP: "5.6.7.8", wantIP: "203.0.113.7"},
+		{name: "rightmost untrusted hop", remoteAddr: "10.1.2.3:5000", forwardedFor: []string{"1.2.3.4, 198.51.100.9, 192.168.1.10"}, wantIP: "198.51.100.9"},
+		{name: "header split over lines", remoteAddr: "10.1.2.3:5000", forwardedFor: []string{"1.2.3.4", "198.51.100.9"}, wantIP: "198.51.100.9"},
+		{name: "garbage hop stops the walk", remoteAddr: "10.1.2.3:5000", forwardedFor: []string{"198.51.100.9, nonsense"}, wantIP: "10.1.2.3"},
+		{name: "X-Real-IP from a trusted proxy", remoteAddr: "192.168.1.10:5000", realIP: "198.51.100.9", wantIP: "198.51.100.9"},
+		{name: "IPv6 clients share a /64 bucket", remoteAddr: "[2001:db8:1:2:aaaa::1]:5000", wantIP: "2001:db8:1:2:aaaa::1", wantKeyByIP: "ip:2001:db8:1:2::/64"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = test.remoteAddr
+			for _, value := range test.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if test.realIP != "" {
+				r.Header.Set("X-Real-IP", test.realIP)
+			}
+
+			var got *http.Request
+			RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+			})).ServeHTTP(httptest.NewRecorder(), r)
+
+			if ip := ClientIP(got); ip != test.wantIP {
+				t.Fatalf("ClientIP = %q, want %q", ip, test.wantIP)
+			}
+			wantKey := test.wantKeyByIP
+			if wantKey == "" {
+				wantKey = "ip:" + test.wantIP
+			}
+			if key := KeyByIP(got); key != wantKey {
+				t.Fatalf("KeyByIP = %q, want %q", key, wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	auth "svm/auth/jwt"
+)
+
+const (
+	RefreshTokenCookie = "svm_refresh"
+	AccessTokenCookie  = "svm_access"
+	CSRFCookie         = "svm_csrf"
+
+	// CSRFHeader must repeat the value of the CSRF cookie on unsafe requests made with session cookies
+	CSRFHeader = "X-CSRF-Token"
+	// SessionModeHeader set to "cookie" asks Login and RefreshToken to use cookies instead of the response body
+	SessionModeHeader = "X-Session-Mode"
+)
+
+// CookieConfig controls the browser session mode
+type CookieConfig struct {
+	Enabled     bool
+	AccessToken bool // access token de cookie'de tutulur, yoksa sadece refresh token
+	Domain      string
+	SameSite    http.SameSite
+}
+
+// Cookies is set in main; the cookie mode is off unless Enabled
+var Cookies = CookieConfig{
+	SameSite: http.SameSiteStrictMode,
+}
+
+// WantsCookieSession reports whether the client asked for the cookie mode and it is enabled
+func WantsCookieSession(r *http.Request) bool {
+	return Cookies.Enabled && r.Header.Get(SessionModeHeader) == "cookie"
+}
+
+// SetSessionCookies stores the refresh token, optionally the access token, and a
+// fresh CSRF token in cookies. The token cookies are HttpOnly; the CSRF cookie is
+// readable by scripts so they can echo it in the CSRFHeader.
+func SetSessionCookies(w http.ResponseWriter, refreshToken, accessToken string) error {
+	csrfToken, err := newCSRFToken()
+	if err != nil {
+		return err
+	}
+
+	// Refresh token sadece /api altındaki refresh ve logout uçlarına gönderilir
+	http.SetCookie(w, sessionCookie(RefreshTokenCookie, refreshToken, "/api", int(auth.RefreshTokenDuration.Seconds()), true))
+	if Cookies.AccessToken {
+		http.SetCookie(w, sessionCookie(AccessTokenCookie, accessToken, "/", int(auth.AccessTokenDuration.Seconds()), true))
+	}
+	http.SetCookie(w, sessionCookie(CSRFCookie, csrfToken, "/", int(auth.RefreshTokenDuration.Seconds()), false))
+	return nil
+}
+
+// ClearSessionCookies removes every session cookie
+func ClearSessionCookies(w http.ResponseWriter) {
+	http.SetCookie(w, sessionCookie(RefreshTokenCookie, "", "/api", -1, true))
+	http.SetCookie(w, sessionCookie(AccessTokenCookie, "", "/", -1, true))
+	http.SetCookie(w, sessionCookie(CSRFCookie, "", "/", -1, false))
+}
+
+// RefreshTokenFromCookie returns the refresh token cookie, if the cookie mode is enabled
+func RefreshTokenFromCookie(r *http.Request) (string, bool) {
+	return cookieValue(r, RefreshTokenCookie)
+}
+
+// AccessTokenFromCookie returns the access token cookie, if the cookie mode is enabled
+func AccessTokenFromCookie(r *http.Request) (string, bool) {
+	if !Cookies.AccessToken {
+		return "", false
+	}
+	return cookieValue(r, AccessTokenCookie)
+}
+
+// CSRFProtection implements the double-submit cookie pattern: unsafe requests
+// that carry a session cookie must send the CSRF cookie's value in CSRFHeader.
+// Requests authenticated only with an Authorization header are not affected,
+// since a browser never attaches that header on its own.
+func CSRFProtection(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		_, hasRefresh := cookieValue(r, RefreshTokenCookie)
+		_, hasAccess := cookieValue(r, AccessTokenCookie)
+		if !hasRefresh && !hasAccess {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		csrfCookie, ok := cookieValue(r, CSRFCookie)
+		csrfHeader := r.Header.Get(CSRFHeader)
+		if !ok || csrfHeader == "" || subtle.ConstantTimeCompare([]byte(csrfCookie), []byte(csrfHeader)) != 1 {
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func cookieValue(r *http.Request, name string) (string, bool) {
+	if !Cookies.Enabled {
+		return "", false
+	}
+	cookie, err := r.Cookie(name)
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+func sessionCookie(name, value, path string, maxAge int, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   Cookies.Domain,
+		MaxAge:   maxAge,
+		HttpOnly: httpOnly,
+		Secure:   true,
+		SameSite: Cookies.SameSite,
+	}
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
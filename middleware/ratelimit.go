@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"svm/auth/authz"
+	authToken "svm/auth/token"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// APIKeyHeader is the header machine clients send their API key in
+const APIKeyHeader = "X-API-Key"
+
+// RateLimitKeyFunc returns the identity a request is counted against
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitPolicy is the limit one route or route group declares
+type RateLimitPolicy struct {
+	Name    string // Redis anahtarlarında kullanılır, politikalar arasında benzersiz olmalı
+	Limit   int
+	Window  time.Duration
+	Key     RateLimitKeyFunc
+	Methods []string // boşsa tüm metodlar sayılır
+}
+
+// KeyByIP counts requests per client IP, and per /64 for IPv6 clients
+func KeyByIP(r *http.Request) string {
+	return "ip:" + ClientNetwork(r)
+}
+
+// KeyByUser counts requests per authenticated user and falls back to the IP
+// for anonymous requests. It must run after JWTAuthentication.
+func KeyByUser(r *http.Request) string {
+	if principal, ok := authz.PrincipalFromRequest(r); ok {
+		return "user:" + strconv.FormatUint(uint64(principal.UserID), 10)
+	}
+	return KeyByIP(r)
+}
+
+// KeyByAPIKey counts requests per API key and falls back to KeyByUser. Only keys
+// APIKeyAuthentication has accepted count, so it must run after that middleware;
+// an unchecked header would give every made-up key a fresh bucket.
+func KeyByAPIKey(r *http.Request) string {
+	if principal, ok := authz.PrincipalFromRequest(r); ok && principal.APIKeyID != 0 {
+		return "key:" + strconv.FormatUint(uint64(principal.APIKeyID), 10)
+	}
+	return KeyByUser(r)
+}
+
+// slidingWindowScript, mevcut ve önceki sabit pencerenin sayaçlarını ağırlıklandırarak
+// kayan pencere tahmini yapar. İstek kabul edilirse mevcut pencerenin sayacı artırılır.
+// Dönüş: {kabul (1/0), tahmini istek sayısı}
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local start = now - (now % window)
+
+local current_key = KEYS[1] .. ":" .. start
+local previous_key = KEYS[1] .. ":" .. (start - window)
+local current = tonumber(redis.call("GET", current_key) or "0")
+local previous = tonumber(redis.call("GET", previous_key) or "0")
+
+local used = math.ceil(previous * (window - (now - start)) / window + current)
+if used >= limit then
+	return {0, used}
+end
+
+redis.call("INCR", current_key)
+redis.call("PEXPIRE", current_key, window * 2)
+return {1, used + 1}
+`)
+
+// RateLimit limits requests matching policy with a sliding window counter in
+// Redis and reports the state in the RateLimit-* headers. When Redis is not
+// reachable requests are let through rather than failing the API.
+func RateLimit(tokenStore *authToken.TokenStore, policy RateLimitPolicy) func(http.Handler) http.Handler {
+	window := policy.Window.Milliseconds()
+	policyHeader := fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Window.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !policy.appliesTo(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			now := time.Now().UnixMilli()
+			key := "ratelimit:" + policy.Name + ":" + policy.Key(r)
+			result, err := slidingWindowScript.Run(r.Context(), tokenStore.RedisClient, []string{key}, policy.Limit, window, now).Int64Slice()
+			if err != nil {
+				log.Printf("Rate limiter %s unavailable: %v", policy.Name, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			allowed, used := result[0] == 1, int(result[1])
+
+			// Mevcut pencerenin bitişine kalan süre, saniye cinsinden yukarı yuvarlanır
+			reset := (window - now%window + 999) / 1000
+			remaining := policy.Limit - used
+			if remaining < 0 {
+				remaining = 0
+			}
+
+			w.Header().Set("RateLimit-Policy", policyHeader)
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("RateLimit-Reset", strconv.FormatInt(reset, 10))
+
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.FormatInt(reset, 10))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (p RateLimitPolicy) appliesTo(method string) bool {
+	if len(p.Methods) == 0 {
+		return true
+	}
+	for _, m := range p.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"svm/models/db_models"
+)
+
+// PromoteAdmin gives the admin role to the user with the email address. Only a
+// verified address counts: anyone can register an address, so promoting an
+// unverified one would hand admin to whoever signed up with it first.
+func PromoteAdmin(db *gorm.DB, email string) error {
+	result := db.Model(&db_models.User{}).
+		Where("LOWER(email) = LOWER(?) AND email_verified_at IS NOT NULL", email).
+		Update("Role", db_models.RoleAdmin)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no user with the verified email address %q", email)
+	}
+	return nil
+}
@@ -1,55 +1,116 @@
 package migrations
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"log"
+	"os"
 	"svm/auth/hashing"
+	"svm/auth/identity"
+	"svm/auth/policy"
 	"svm/models/db_models"
+	"time"
 )
 
 func CreateDb() (*gorm.DB, error) {
 	dsn := "host=localhost user=postgres password=123456 dbname=swm port=5432 sslmode=disable TimeZone=Asia/Shanghai"
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info), // Sorguları loglama
+		// Unique ihlalleri gorm.ErrDuplicatedKey olarak döner, handler'lar 409 verebilsin
+		TranslateError: true,
 	})
-	err = db.AutoMigrate(&db_models.User{}, &db_models.Friend{}, &db_models.UserLocation{})
+	err = db.AutoMigrate(&db_models.User{}, &db_models.Friend{}, &db_models.FriendRequest{}, &db_models.UserLocation{}, &db_models.RecoveryCode{}, &db_models.WebAuthnCredential{}, &db_models.AuditLog{}, &db_models.APIKey{}, &db_models.Identity{}, &db_models.SecurityEvent{})
 	if err != nil {
 		return nil, err
 	}
+	normalizeEmails(db)
 	seedData(db)
+
+	// Kimlik tablosundan önce açılmış hesapların şifre ve passkey'leri kimlik olarak eklenir
+	if err := identity.Backfill(db); err != nil {
+		return nil, err
+	}
 	return db, err
 }
 
+// normalizeEmails küçük harfe çevrilmeden kaydedilmiş adresleri düzeltir ve adreslerin büyük/küçük
+// harf farkıyla iki kez kaydedilmesini engelleyen indeksi oluşturur. Sadece harf farkı olan iki hesap
+// varsa bunlar elle birleştirilmelidir, o zaman hata loglanır ve uygulama yine açılır.
+func normalizeEmails(db *gorm.DB) {
+	if err := db.Exec("UPDATE users SET email = LOWER(email) WHERE email <> LOWER(email)").Error; err != nil {
+		log.Printf("Failed to lower-case email addresses: %v", err)
+		return
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email)) WHERE email <> ''").Error; err != nil {
+		log.Printf("Failed to create the case-insensitive email index: %v", err)
+	}
+}
+
 // Örnek verilerin eklenmesi
 func seedData(db *gorm.DB) {
+	verifiedAt := time.Now()
+
 	john := db_models.User{
-		Name:         "John Doe",
-		Email:        "john@mail.com",
-		HomeAddress:  "123 Main St",
-		ShareAddress: true,
+		Name:            "John Doe",
+		Email:           "john@mail.com",
+		HomeAddress:     "123 Main St",
+		ShareAddress:    true,
+		EmailVerifiedAt: &verifiedAt,
 	}
-	err := hashing.SetPassword(&john, "12")
+	johnPassword, johnGenerated := seedPassword("SEED_JOHN_PASSWORD", john)
+	err := hashing.SetPassword(&john, johnPassword)
 	if err != nil {
 		fmt.Println("Failed to set password	hash:", err)
 	}
 
 	jane := db_models.User{
-		Name:         "Jane Smith",
-		Email:        "jane@mail.com",
-		HomeAddress:  "456 Elm St",
-		ShareAddress: true,
+		Name:            "Jane Smith",
+		Email:           "jane@mail.com",
+		HomeAddress:     "456 Elm St",
+		ShareAddress:    true,
+		EmailVerifiedAt: &verifiedAt,
 	}
-	err = hashing.SetPassword(&jane, "21")
+	janePassword, janeGenerated := seedPassword("SEED_JANE_PASSWORD", jane)
+	err = hashing.SetPassword(&jane, janePassword)
 	if err != nil {
 		fmt.Println("Failed to set password	hash:", err)
 	}
 
-	db.Create(&john)
-	db.Create(&jane)
+	// Kullanıcılar zaten varsa oluşturma başarısız olur, üretilen şifre de kullanılmamış olur
+	if db.Create(&john).Error == nil && johnGenerated {
+		log.Printf("Seeded %s with generated password %s", john.Email, johnPassword)
+	}
+	if db.Create(&jane).Error == nil && janeGenerated {
+		log.Printf("Seeded %s with generated password %s", jane.Email, janePassword)
+	}
 
 	// Kullanıcıları birbirine arkadaş olarak ekleme
 	db.Model(&john).Association("Friends").Append(&jane)
 	db.Model(&jane).Association("Friends").Append(&john)
 }
+
+// seedPassword örnek kullanıcının şifresini env'den okur. Değişken yoksa veya şifre politikaya
+// uymuyorsa rastgele bir şifre üretilir; ikinci dönüş değeri şifrenin üretildiğini belirtir.
+func seedPassword(env string, user db_models.User) (string, bool) {
+	if password := os.Getenv(env); password != "" {
+		if err := policy.DefaultPolicy.Check(password, user.Name, user.Email); err == nil {
+			return password, false
+		}
+		log.Printf("%s does not meet the password policy, generating a password instead", env)
+	}
+
+	for {
+		buf := make([]byte, 12)
+		if _, err := rand.Read(buf); err != nil {
+			log.Fatalf("Failed to generate seed password: %v", err)
+		}
+		password := base64.RawURLEncoding.EncodeToString(buf)
+		if policy.DefaultPolicy.Check(password, user.Name, user.Email) == nil {
+			return password, true
+		}
+	}
+}
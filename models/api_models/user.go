@@ -1,5 +1,7 @@
 package api_models
 
+import "time"
+
 // UserLocationResponse represents the structure of the user location data in the response
 type UserLocationResponse struct {
 	ID        uint    `json:"id"`
@@ -25,10 +27,18 @@ type FriendResponse struct {
 
 // FriendRequest represents the structure for the friend request payload
 type FriendRequest struct {
-	UserID   uint `json:"user_id"`
+	UserID   uint `json:"user_id"` // opsiyonel, varsayılan olarak token sahibi
 	FriendID uint `json:"friend_id"`
 }
 
+// FriendRequestResponse represents a pending friend request sent to the authenticated user
+type FriendRequestResponse struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"` // isteği gönderen kullanıcı
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // CreateUserRequest represents the expected payload for creating a user
 type CreateUserRequest struct {
 	Email        string `json:"email"`
@@ -46,7 +56,7 @@ type UpdateUserRequest struct {
 }
 
 type UserLocationRequest struct {
-	UserID    uint    `json:"user_id"`
+	UserID    uint    `json:"user_id"` // opsiyonel, verilirse token sahibi olmalı
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 }
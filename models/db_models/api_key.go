@@ -0,0 +1,19 @@
+package db_models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// APIKey modeli, makine istemcileri için kişisel erişim anahtarı. Anahtarın kendisi saklanmaz, sadece hash'i.
+type APIKey struct {
+	gorm.Model `swaggerignore:"true"`
+	UserID     uint   `gorm:"index;not null"`
+	Name       string `gorm:"size:100;not null"`
+	Prefix     string `gorm:"size:16;not null"` // listede anahtarı tanımak için ilk karakterler
+	KeyHash    string `gorm:"size:64;uniqueIndex;not null"`
+	Scopes     string `gorm:"size:255;not null"` // boşlukla ayrılmış liste
+	ExpiresAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
@@ -0,0 +1,13 @@
+package db_models
+
+import "gorm.io/gorm"
+
+// AuditLog modeli, yöneticilerin yaptığı her işlemin kaydı
+type AuditLog struct {
+	gorm.Model   `swaggerignore:"true"`
+	ActorID      uint   `gorm:"index;not null"`
+	Action       string `gorm:"size:50;not null"`
+	TargetUserID uint   `gorm:"index"`
+	Details      string `gorm:"size:500"`
+	IP           string `gorm:"size:64"`
+}
@@ -0,0 +1,12 @@
+package db_models
+
+import "gorm.io/gorm"
+
+// FriendRequest modeli, alıcı kabul edene kadar bekleyen arkadaşlık isteği.
+// Arkadaşlık (Friend satırları) ancak kabulden sonra oluşur.
+type FriendRequest struct {
+	gorm.Model `swaggerignore:"true"`
+	UserID     uint `gorm:"not null;uniqueIndex:idx_friend_request_pair"`       // isteği gönderen
+	FriendID   uint `gorm:"not null;uniqueIndex:idx_friend_request_pair;index"` // isteği alan
+	User       User `gorm:"foreignKey:UserID" swaggerignore:"true"`
+}
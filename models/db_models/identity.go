@@ -0,0 +1,24 @@
+package db_models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Identity modeli, kullanıcının giriş yapabildiği yöntemlerden biri. Bir kullanıcının birden fazla kimliği olabilir.
+type Identity struct {
+	gorm.Model `swaggerignore:"true"`
+	UserID     uint         `gorm:"index;not null"`
+	Type       IdentityType `gorm:"size:20;not null;uniqueIndex:idx_identity_subject"`
+	Issuer     string       `gorm:"size:255;not null;default:'';uniqueIndex:idx_identity_subject"` // sadece OIDC için
+	Subject    string       `gorm:"size:255;not null;uniqueIndex:idx_identity_subject"`
+	Name       string       `gorm:"size:255"` // listede gösterilen ad, ör. sağlayıcıdaki e-posta veya passkey adı
+}
+
+type IdentityType string
+
+const (
+	IdentityPassword IdentityType = "password" // Subject kullanıcı ID'si, giriş yine e-posta ile yapılır
+	IdentityOIDC     IdentityType = "oidc"     // Subject sağlayıcının sub claim'i
+	IdentityPasskey  IdentityType = "passkey"  // Subject base64url credential ID
+	IdentityPhone    IdentityType = "phone"    // Subject E.164 telefon numarası
+)
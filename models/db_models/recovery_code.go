@@ -0,0 +1,14 @@
+package db_models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// RecoveryCode modeli, iki adımlı doğrulama için tek kullanımlık kurtarma kodu
+type RecoveryCode struct {
+	gorm.Model `swaggerignore:"true"`
+	UserID     uint   `gorm:"index;not null"`
+	CodeHash   string `gorm:"size:64;not null"`
+	UsedAt     *time.Time
+}
@@ -0,0 +1,17 @@
+package db_models
+
+import "gorm.io/gorm"
+
+// SecurityEvent modeli, bir hesapta olan güvenlik açısından önemli her işlemin kaydı
+type SecurityEvent struct {
+	gorm.Model `swaggerignore:"true"`
+	UserID     uint    `gorm:"index:idx_security_events_user_type"` // bilinmeyen e-posta ile denemelerde 0
+	Type       string  `gorm:"size:50;not null;index:idx_security_events_user_type"`
+	Email      string  `gorm:"size:100"`
+	IP         string  `gorm:"size:64"`
+	UserAgent  string  `gorm:"size:255"`
+	DeviceName string  `gorm:"size:100"`
+	Lat        float64 // istemci konum göndermediyse 0
+	Lng        float64
+	Details    string `gorm:"size:500"`
+}
@@ -1,15 +1,36 @@
 package db_models
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"time"
+)
 
 // User modeli
 type User struct {
-	gorm.Model   `swaggerignore:"true"`
-	Name         string         `gorm:"size:100;not null"`
-	Email        string         `gorm:"size:100;unique;not null"`
-	PasswordHash string         `gorm:"not null"`
-	HomeAddress  string         `gorm:"size:255"`
-	ShareAddress bool           `gorm:"not null;default:false"`
-	Friends      []*User        `gorm:"many2many:friends"`
-	Locations    []UserLocation `gorm:"foreignKey:UserID"`
+	gorm.Model      `swaggerignore:"true"`
+	Name            string         `gorm:"size:100;not null"`
+	Email           string         `gorm:"size:100;not null;uniqueIndex:idx_users_email,where:email <> ''"` // telefonla kayıt olanlarda boş olabilir
+	PasswordHash    string         `gorm:"not null"`
+	HomeAddress     string         `gorm:"size:255"`
+	ShareAddress    bool           `gorm:"not null;default:false"`
+	Friends         []*User        `gorm:"many2many:friends"`
+	Locations       []UserLocation `gorm:"foreignKey:UserID"`
+	TOTPSecret      string         `gorm:"size:64" json:"-"`
+	TOTPEnabled     bool           `gorm:"not null;default:false"`
+	TOTPLastStep    int64          `gorm:"not null;default:0" json:"-"`
+	EmailVerifiedAt *time.Time
+	Role            Role `gorm:"size:20;not null;default:user"`
+	SuspendedAt     *time.Time
+	SuspendedReason string `gorm:"size:255"`
+	Phone           string `gorm:"size:20;not null;default:'';uniqueIndex:idx_users_phone,where:phone <> ''"` // E.164, sadece doğrulandıktan sonra yazılır
+	PhoneVerifiedAt *time.Time
+	SMSMFAEnabled   bool `gorm:"not null;default:false"`
 }
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
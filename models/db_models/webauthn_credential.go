@@ -0,0 +1,22 @@
+package db_models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// WebAuthnCredential modeli, kullanıcıya kayıtlı bir passkey
+type WebAuthnCredential struct {
+	gorm.Model      `swaggerignore:"true"`
+	UserID          uint   `gorm:"index;not null"`
+	Name            string `gorm:"size:100"`
+	CredentialID    []byte `gorm:"uniqueIndex;not null"`
+	PublicKey       []byte `gorm:"not null"`
+	AttestationType string `gorm:"size:32"`
+	AAGUID          []byte
+	SignCount       uint32
+	Transports      string `gorm:"size:255"` // virgülle ayrılmış liste
+	BackupEligible  bool   `gorm:"not null;default:false"`
+	BackupState     bool   `gorm:"not null;default:false"`
+	LastUsedAt      *time.Time
+}
@@ -0,0 +1,23 @@
+package sms
+
+import (
+	"log"
+)
+
+// SMSSender sends text messages. A provider backed implementation is used in
+// production, LogSender for local runs.
+type SMSSender interface {
+	Send(to, body string) error
+}
+
+// LogSender writes messages to the log instead of delivering them
+type LogSender struct{}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Send(to, body string) error {
+	log.Printf("SMS to %s: %s", to, body)
+	return nil
+}